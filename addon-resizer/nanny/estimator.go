@@ -28,19 +28,100 @@ const (
 	eps = float64(0.01)
 )
 
+// Dimension is a measure of the cluster size that resources can scale with.
+type Dimension string
+
+const (
+	// NodesDimension is the number of nodes.
+	NodesDimension Dimension = "nodes"
+	// PodsDimension is the number of pods in all namespaces.
+	PodsDimension Dimension = "pods"
+	// ServicesDimension is the number of services in all namespaces.
+	ServicesDimension Dimension = "services"
+	// EndpointsDimension is the number of ready endpoint addresses of all services.
+	EndpointsDimension Dimension = "endpoints"
+)
+
+// AvailableDimensions lists all dimensions of the cluster size.
+var AvailableDimensions = []Dimension{NodesDimension, PodsDimension, ServicesDimension, EndpointsDimension}
+
+// ParseDimension returns the dimension with the given name.
+func ParseDimension(name string) (Dimension, error) {
+	for _, dimension := range AvailableDimensions {
+		if string(dimension) == name {
+			return dimension, nil
+		}
+	}
+	return "", fmt.Errorf("unknown dimension %s, expected one of %v", name, AvailableDimensions)
+}
+
+// ClusterSize is a snapshot of the cluster size along all dimensions.
+type ClusterSize struct {
+	Nodes, Pods, Services, Endpoints uint64
+}
+
+// Get returns the size of the cluster along the dimension. The empty dimension means nodes.
+func (s ClusterSize) Get(dimension Dimension) uint64 {
+	switch dimension {
+	case PodsDimension:
+		return s.Pods
+	case ServicesDimension:
+		return s.Services
+	case EndpointsDimension:
+		return s.Endpoints
+	}
+	return s.Nodes
+}
+
 // Resource defines the name of a resource, the quantity, and the marginal value.
+// ExtraPerNode is added for each unit of Dimension, which defaults to nodes.
+// Base and ExtraPerNode define the request. The limit is equal to the request,
+// unless LimitBase or LimitRatio is set. The limit is never lower than the request.
 type Resource struct {
-	Base, ExtraPerNode resource.Quantity
-	Name               api.ResourceName
+	Base         resource.Quantity `json:"base"`
+	ExtraPerNode resource.Quantity `json:"extraPerNode"`
+	Name         api.ResourceName  `json:"name"`
+	Dimension    Dimension         `json:"dimension,omitempty"`
+	// LimitBase and LimitExtraPerNode define the limit independently of the request.
+	// LimitExtraPerNode is only valid together with LimitBase.
+	LimitBase         *resource.Quantity `json:"limitBase,omitempty"`
+	LimitExtraPerNode resource.Quantity  `json:"limitExtraPerNode"`
+	// LimitRatio sets the limit to the request multiplied by the ratio.
+	LimitRatio float64 `json:"limitRatio,omitempty"`
+}
+
+// resourceDimensions returns the dimensions that the resources scale with.
+func resourceDimensions(resources []Resource) []Dimension {
+	var dimensions []Dimension
+	seen := make(map[Dimension]bool)
+	for _, r := range resources {
+		dimension := r.Dimension
+		if dimension == "" {
+			dimension = NodesDimension
+		}
+		if !seen[dimension] {
+			seen[dimension] = true
+			dimensions = append(dimensions, dimension)
+		}
+	}
+	return dimensions
 }
 
-// LinearEstimator estimates the amount of resources as r = base + extra*nodes.
+// LinearEstimator estimates the amount of resources as r = base + extra*size.
 type LinearEstimator struct {
 	Resources []Resource
 }
 
-func (e LinearEstimator) scaleWithNodes(numNodes uint64) *api.ResourceRequirements {
-	return calculateResources(numNodes, e.Resources)
+func (e LinearEstimator) scaleWithClusterSize(size ClusterSize) *api.ResourceRequirements {
+	return calculateResources(size, e.Resources)
+}
+
+func (e LinearEstimator) dimensions() []Dimension {
+	return resourceDimensions(e.Resources)
+}
+
+func (e LinearEstimator) snapshot() ResourceEstimator {
+	return e
 }
 
 // ExponentialEstimator estimates the amount of resources in the way that
@@ -51,33 +132,68 @@ type ExponentialEstimator struct {
 	ScaleFactor float64
 }
 
-func (e ExponentialEstimator) scaleWithNodes(numNodes uint64) *api.ResourceRequirements {
+func (e ExponentialEstimator) scaleWithClusterSize(size ClusterSize) *api.ResourceRequirements {
+	rounded := ClusterSize{
+		Nodes:     e.roundUp(size.Nodes),
+		Pods:      e.roundUp(size.Pods),
+		Services:  e.roundUp(size.Services),
+		Endpoints: e.roundUp(size.Endpoints),
+	}
+	return calculateResources(rounded, e.Resources)
+}
+
+func (e ExponentialEstimator) dimensions() []Dimension {
+	return resourceDimensions(e.Resources)
+}
+
+func (e ExponentialEstimator) snapshot() ResourceEstimator {
+	return e
+}
+
+// roundUp returns the smallest power of ScaleFactor times 16 that is not lower than num.
+func (e ExponentialEstimator) roundUp(num uint64) uint64 {
 	n := uint64(16)
-	for n < numNodes {
+	for n < num {
 		n = uint64(float64(n)*e.ScaleFactor + eps)
 	}
-	return calculateResources(n, e.Resources)
+	return n
 }
 
-func calculateResources(numNodes uint64, resources []Resource) *api.ResourceRequirements {
+func calculateResources(size ClusterSize, resources []Resource) *api.ResourceRequirements {
 	limits := make(api.ResourceList)
 	requests := make(api.ResourceList)
 	for _, r := range resources {
-		// Since we want to enable passing values smaller than e.g. 1 millicore per node,
-		// we need to have some more hacky solution here than operating on MilliValues.
-		perNodeString := r.ExtraPerNode.String()
-		var perNode float64
-		read, _ := fmt.Sscanf(perNodeString, "%f", &perNode)
-		overhead := resource.MustParse(fmt.Sprintf("%f%s", perNode*float64(numNodes), perNodeString[read:]))
-
-		newRes := r.Base
-		newRes.Add(overhead)
-
-		limits[r.Name] = newRes
-		requests[r.Name] = newRes
+		count := size.Get(r.Dimension)
+		request := scaleQuantity(r.Base, r.ExtraPerNode, count)
+		limit := request
+		if r.LimitBase != nil {
+			limit = scaleQuantity(*r.LimitBase, r.LimitExtraPerNode, count)
+		} else if r.LimitRatio > 0 {
+			limit = *resource.NewMilliQuantity(int64(float64(request.MilliValue())*r.LimitRatio+0.5), request.Format)
+		}
+		if limit.Cmp(request) < 0 {
+			limit = request
+		}
+
+		limits[r.Name] = limit
+		requests[r.Name] = request
 	}
 	return &api.ResourceRequirements{
 		Limits:   limits,
 		Requests: requests,
 	}
 }
+
+// scaleQuantity returns base + extra*count.
+func scaleQuantity(base, extra resource.Quantity, count uint64) resource.Quantity {
+	// Since we want to enable passing values smaller than e.g. 1 millicore per node,
+	// we need to have some more hacky solution here than operating on MilliValues.
+	perNodeString := extra.String()
+	var perNode float64
+	read, _ := fmt.Sscanf(perNodeString, "%f", &perNode)
+	overhead := resource.MustParse(fmt.Sprintf("%f%s", perNode*float64(count), perNodeString[read:]))
+
+	newRes := base
+	newRes.Add(overhead)
+	return newRes
+}
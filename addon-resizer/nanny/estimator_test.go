@@ -230,7 +230,7 @@ func TestEstimateResources(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		got := tc.e.scaleWithNodes(tc.numNodes)
+		got := tc.e.scaleWithClusterSize(ClusterSize{Nodes: tc.numNodes})
 		want := &api.ResourceRequirements{
 			Limits:   tc.limits,
 			Requests: tc.requests,
@@ -239,3 +239,114 @@ func TestEstimateResources(t *testing.T) {
 		verifyResources(t, "requests", got.Requests, want.Limits)
 	}
 }
+
+func TestEstimateResourcesWithDimensions(t *testing.T) {
+	estimator := LinearEstimator{
+		Resources: []Resource{
+			{
+				Base:         resource.MustParse("0.3"),
+				ExtraPerNode: resource.MustParse("1"),
+				Name:         "cpu",
+			},
+			{
+				Base:         resource.MustParse("30Mi"),
+				ExtraPerNode: resource.MustParse("1Mi"),
+				Name:         "memory",
+				Dimension:    PodsDimension,
+			},
+			{
+				Base:         resource.MustParse("30Gi"),
+				ExtraPerNode: resource.MustParse("1Gi"),
+				Name:         "storage",
+				Dimension:    EndpointsDimension,
+			},
+		},
+	}
+	dimensions := estimator.dimensions()
+	if len(dimensions) != 3 || dimensions[0] != NodesDimension || dimensions[1] != PodsDimension || dimensions[2] != EndpointsDimension {
+		t.Errorf("dimensions got %v, want [nodes pods endpoints]", dimensions)
+	}
+
+	want := api.ResourceList{
+		"cpu":     resource.MustParse("3.3"),
+		"memory":  resource.MustParse("40Mi"),
+		"storage": resource.MustParse("50Gi"),
+	}
+	got := estimator.scaleWithClusterSize(ClusterSize{Nodes: 3, Pods: 10, Services: 5, Endpoints: 20})
+	verifyResources(t, "limits", got.Limits, want)
+	verifyResources(t, "requests", got.Requests, want)
+
+	exponential := ExponentialEstimator{Resources: estimator.Resources, ScaleFactor: 1.5}
+	want = api.ResourceList{
+		"cpu":     resource.MustParse("16.3"),
+		"memory":  resource.MustParse("54Mi"),
+		"storage": resource.MustParse("66Gi"),
+	}
+	got = exponential.scaleWithClusterSize(ClusterSize{Nodes: 3, Pods: 20, Services: 5, Endpoints: 30})
+	verifyResources(t, "limits", got.Limits, want)
+	verifyResources(t, "requests", got.Requests, want)
+}
+
+func TestParseDimension(t *testing.T) {
+	for _, dimension := range AvailableDimensions {
+		parsed, err := ParseDimension(string(dimension))
+		if err != nil || parsed != dimension {
+			t.Errorf("ParseDimension(%s) got %s, %v", dimension, parsed, err)
+		}
+	}
+	if _, err := ParseDimension("volumes"); err == nil {
+		t.Errorf("ParseDimension(volumes) didn't fail")
+	}
+}
+
+func TestEstimateLimitsAndRequests(t *testing.T) {
+	limitBase := resource.MustParse("1")
+	estimator := LinearEstimator{
+		Resources: []Resource{
+			{
+				Base:              resource.MustParse("0.3"),
+				ExtraPerNode:      resource.MustParse("100m"),
+				Name:              "cpu",
+				LimitBase:         &limitBase,
+				LimitExtraPerNode: resource.MustParse("200m"),
+			},
+			{
+				Base:         resource.MustParse("30Mi"),
+				ExtraPerNode: resource.MustParse("1Mi"),
+				Name:         "memory",
+				LimitRatio:   1.5,
+			},
+			{
+				Base:         resource.MustParse("30Gi"),
+				ExtraPerNode: resource.MustParse("1Gi"),
+				Name:         "storage",
+			},
+		},
+	}
+	got := estimator.scaleWithClusterSize(ClusterSize{Nodes: 2})
+	verifyResources(t, "requests", got.Requests, api.ResourceList{
+		"cpu":     resource.MustParse("0.5"),
+		"memory":  resource.MustParse("32Mi"),
+		"storage": resource.MustParse("32Gi"),
+	})
+	verifyResources(t, "limits", got.Limits, api.ResourceList{
+		"cpu":     resource.MustParse("1.4"),
+		"memory":  resource.MustParse("48Mi"),
+		"storage": resource.MustParse("32Gi"),
+	})
+
+	// The limit is never lower than the request.
+	lowLimitBase := resource.MustParse("0.1")
+	lowLimitEstimator := LinearEstimator{
+		Resources: []Resource{
+			{
+				Base:         resource.MustParse("0.3"),
+				ExtraPerNode: resource.MustParse("100m"),
+				Name:         "cpu",
+				LimitBase:    &lowLimitBase,
+			},
+		},
+	}
+	got = lowLimitEstimator.scaleWithClusterSize(ClusterSize{Nodes: 2})
+	verifyResources(t, "limits", got.Limits, api.ResourceList{"cpu": resource.MustParse("0.5")})
+}
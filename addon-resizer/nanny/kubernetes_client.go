@@ -18,18 +18,52 @@ package nanny
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
+	log "github.com/golang/glog"
 	api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
 	apiv1 "k8s.io/kubernetes/pkg/api/v1"
+	"k8s.io/kubernetes/pkg/apis/extensions/v1beta1"
 	client "k8s.io/kubernetes/pkg/client/clientset_generated/release_1_3"
 )
 
 type kubernetesClient struct {
 	namespace, deployment, pod, container string
 	clientset                             *client.Clientset
+	nodes                                 *NodeWatcher
 }
 
-func (k *kubernetesClient) CountNodes() (uint64, error) {
+func (k *kubernetesClient) ClusterSize(dimensions []Dimension) (ClusterSize, error) {
+	size := ClusterSize{}
+	for _, dimension := range dimensions {
+		var err error
+		switch dimension {
+		case NodesDimension:
+			size.Nodes, err = k.countNodes()
+		case PodsDimension:
+			size.Pods, err = k.countPods()
+		case ServicesDimension:
+			size.Services, err = k.countServices()
+		case EndpointsDimension:
+			size.Endpoints, err = k.countEndpoints()
+		default:
+			err = fmt.Errorf("Unknown dimension %s.", dimension)
+		}
+		if err != nil {
+			return ClusterSize{}, err
+		}
+	}
+	return size, nil
+}
+
+func (k *kubernetesClient) countNodes() (uint64, error) {
+	if k.nodes != nil {
+		if count, synced := k.nodes.Count(); synced {
+			return count, nil
+		}
+	}
 	opt := api.ListOptions{Watch: false}
 
 	nodes, err := k.clientset.CoreClient.Nodes().List(opt)
@@ -39,13 +73,48 @@ func (k *kubernetesClient) CountNodes() (uint64, error) {
 	return uint64(len(nodes.Items)), nil
 }
 
-func (k *kubernetesClient) ContainerResources() (*apiv1.ResourceRequirements, error) {
-	pod, err := k.clientset.CoreClient.Pods(k.namespace).Get(k.pod)
+func (k *kubernetesClient) countPods() (uint64, error) {
+	opt := api.ListOptions{Watch: false}
+
+	pods, err := k.clientset.CoreClient.Pods(api.NamespaceAll).List(opt)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(len(pods.Items)), nil
+}
+
+func (k *kubernetesClient) countServices() (uint64, error) {
+	opt := api.ListOptions{Watch: false}
 
+	services, err := k.clientset.CoreClient.Services(api.NamespaceAll).List(opt)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(len(services.Items)), nil
+}
+
+func (k *kubernetesClient) countEndpoints() (uint64, error) {
+	opt := api.ListOptions{Watch: false}
+
+	endpoints, err := k.clientset.CoreClient.Endpoints(api.NamespaceAll).List(opt)
+	if err != nil {
+		return 0, err
+	}
+	count := uint64(0)
+	for _, e := range endpoints.Items {
+		for _, subset := range e.Subsets {
+			count += uint64(len(subset.Addresses))
+		}
+	}
+	return count, nil
+}
+
+func (k *kubernetesClient) ContainerResources() (*apiv1.ResourceRequirements, error) {
+	containers, err := k.containers()
 	if err != nil {
 		return nil, err
 	}
-	for _, container := range pod.Spec.Containers {
+	for _, container := range containers {
 		if container.Name == k.container {
 			return &container.Resources, nil
 		}
@@ -53,6 +122,23 @@ func (k *kubernetesClient) ContainerResources() (*apiv1.ResourceRequirements, er
 	return nil, fmt.Errorf("Container %s was not found in deployment %s in namespace %s.", k.container, k.deployment, k.namespace)
 }
 
+// containers returns the containers of the pod, or of the deployment's pod
+// template if no pod is given.
+func (k *kubernetesClient) containers() ([]apiv1.Container, error) {
+	if k.pod == "" {
+		dep, err := k.clientset.Extensions().Deployments(k.namespace).Get(k.deployment)
+		if err != nil {
+			return nil, err
+		}
+		return dep.Spec.Template.Spec.Containers, nil
+	}
+	pod, err := k.clientset.CoreClient.Pods(k.namespace).Get(k.pod)
+	if err != nil {
+		return nil, err
+	}
+	return pod.Spec.Containers, nil
+}
+
 func (k *kubernetesClient) UpdateDeployment(resources *apiv1.ResourceRequirements) error {
 	// First, get the Deployment.
 	dep, err := k.clientset.Extensions().Deployments(k.namespace).Get(k.deployment)
@@ -65,21 +151,80 @@ func (k *kubernetesClient) UpdateDeployment(resources *apiv1.ResourceRequirement
 		if container.Name == k.container {
 			// Update the deployment.
 			dep.Spec.Template.Spec.Containers[i].Resources = *resources
-			_, err = k.clientset.ExtensionsClient.Deployments(k.namespace).Update(dep)
-			return err
+			updated, err := k.clientset.ExtensionsClient.Deployments(k.namespace).Update(dep)
+			if err != nil {
+				return err
+			}
+			k.recordResize(updated, &container.Resources, resources)
+			return nil
 		}
 	}
 
 	return fmt.Errorf("Container %s was not found in the deployment %s in namespace %s.", k.container, k.deployment, k.namespace)
 }
 
+// recordResize records an event on the deployment about the resize of the
+// container. Failures are only logged, since the deployment is already updated.
+func (k *kubernetesClient) recordResize(dep *v1beta1.Deployment, old, new *apiv1.ResourceRequirements) {
+	now := unversioned.Now()
+	event := &apiv1.Event{
+		ObjectMeta: apiv1.ObjectMeta{
+			Name:      fmt.Sprintf("%v.%x", dep.Name, now.UnixNano()),
+			Namespace: dep.Namespace,
+		},
+		InvolvedObject: apiv1.ObjectReference{
+			Kind:            "Deployment",
+			APIVersion:      "extensions/v1beta1",
+			Namespace:       dep.Namespace,
+			Name:            dep.Name,
+			UID:             dep.UID,
+			ResourceVersion: dep.ResourceVersion,
+		},
+		Reason: "Resized",
+		Message: fmt.Sprintf("Resized container %s from requests %s, limits %s to requests %s, limits %s",
+			k.container, formatResourceList(old.Requests), formatResourceList(old.Limits),
+			formatResourceList(new.Requests), formatResourceList(new.Limits)),
+		Source:         apiv1.EventSource{Component: "addon-resizer"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Type:           apiv1.EventTypeNormal,
+	}
+	if _, err := k.clientset.CoreClient.Events(dep.Namespace).Create(event); err != nil {
+		log.Errorf("Failed to record resize event on deployment %s in namespace %s: %v", dep.Name, dep.Namespace, err)
+	}
+}
+
+// formatResourceList formats the resources as name=quantity pairs sorted by name.
+func formatResourceList(resources apiv1.ResourceList) string {
+	var pairs []string
+	for name, quantity := range resources {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, quantity.String()))
+	}
+	sort.Strings(pairs)
+	return "[" + strings.Join(pairs, " ") + "]"
+}
+
 // NewKubernetesClient gives a KubernetesClient with the given dependencies.
-func NewKubernetesClient(namespace, deployment, pod, container string, clientset *client.Clientset) KubernetesClient {
+// If pod is empty, the container resources are read from the deployment. If
+// nodes is not nil, the nodes are counted by the watcher once it has synced.
+func NewKubernetesClient(namespace, deployment, pod, container string, clientset *client.Clientset, nodes *NodeWatcher) KubernetesClient {
 	return &kubernetesClient{
 		namespace:  namespace,
 		deployment: deployment,
 		pod:        pod,
 		container:  container,
 		clientset:  clientset,
+		nodes:      nodes,
+	}
+}
+
+// NewClusterSizer gives a ClusterSizer that measures the cluster with the
+// clientset. If nodes is not nil, the nodes are counted by the watcher once it
+// has synced.
+func NewClusterSizer(clientset *client.Clientset, nodes *NodeWatcher) ClusterSizer {
+	return &kubernetesClient{
+		clientset: clientset,
+		nodes:     nodes,
 	}
 }
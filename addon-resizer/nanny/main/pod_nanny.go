@@ -41,6 +41,20 @@ var (
 	baseStorage    = flag.String("storage", noValue, "The base storage resource requirement.")
 	storagePerNode = flag.String("extra-storage", "0Gi", "The amount of storage to add per node.")
 	threshold      = flag.Int("threshold", 0, "A number between 0-100. The dependent's resources are rewritten when they deviate from expected by more than threshold.")
+	// Flags to choose the cluster size dimension that the extra resources are added per.
+	cpuDimension     = flag.String("cpu-dimension", "nodes", "The dimension of the cluster size that extra-cpu is added per: nodes, pods, services or endpoints.")
+	memoryDimension  = flag.String("memory-dimension", "nodes", "The dimension of the cluster size that extra-memory is added per: nodes, pods, services or endpoints.")
+	storageDimension = flag.String("storage-dimension", "nodes", "The dimension of the cluster size that extra-storage is added per: nodes, pods, services or endpoints.")
+	// Flags to define the resource limits. By default the limits are equal to the requests.
+	cpuLimit            = flag.String("cpu-limit", noValue, "The base CPU limit. If set, the CPU limit is computed independently of the request.")
+	cpuLimitPerNode     = flag.String("extra-cpu-limit", "0", "The amount of CPU limit to add per node, used with cpu-limit.")
+	cpuLimitRatio       = flag.Float64("cpu-limit-ratio", 0, "If set, the CPU limit is the CPU request multiplied by the ratio, which must be at least 1.")
+	memoryLimit         = flag.String("memory-limit", noValue, "The base memory limit. If set, the memory limit is computed independently of the request.")
+	memoryLimitPerNode  = flag.String("extra-memory-limit", "0Mi", "The amount of memory limit to add per node, used with memory-limit.")
+	memoryLimitRatio    = flag.Float64("memory-limit-ratio", 0, "If set, the memory limit is the memory request multiplied by the ratio, which must be at least 1.")
+	storageLimit        = flag.String("storage-limit", noValue, "The base storage limit. If set, the storage limit is computed independently of the request.")
+	storageLimitPerNode = flag.String("extra-storage-limit", "0Gi", "The amount of storage limit to add per node, used with storage-limit.")
+	storageLimitRatio   = flag.Float64("storage-limit-ratio", 0, "If set, the storage limit is the storage request multiplied by the ratio, which must be at least 1.")
 	// Flags to identify the container to nanny.
 	podNamespace  = flag.String("namespace", os.Getenv("MY_POD_NAMESPACE"), "The namespace of the ward. This defaults to the nanny pod's own namespace.")
 	deployment    = flag.String("deployment", "", "The name of the deployment being monitored. This is required.")
@@ -48,7 +62,13 @@ var (
 	containerName = flag.String("container", "pod-nanny", "The name of the container to watch. This defaults to the nanny itself.")
 	// Flags to control runtime behavior.
 	pollPeriod = time.Millisecond * time.Duration(*flag.Int("poll-period", 10000, "The time, in milliseconds, to poll the dependent container."))
-	estimator  = flag.String("estimator", "linear", "The estimator to use. Currently supported: linear, exponential")
+	estimator  = flag.String("estimator", "linear", "The estimator to use. Currently supported: linear, exponential, step")
+	stepTable  = flag.String("step-table", "", "The path to the YAML or JSON table of the step estimator, e.g. mounted from a ConfigMap. The file is checked for changes every poll period.")
+	configFile = flag.String("config", "", "The path to a YAML or JSON list of targets to nanny. If set, the flags identifying the container and defining its resources are ignored.")
+	// Flags to limit how often the dependent is resized.
+	scaleUpCooldown   = flag.Duration("scale-up-cooldown", 0, "The time to wait after a resize, or the start of the nanny, before growing the resources. Disabled by default.")
+	scaleDownCooldown = flag.Duration("scale-down-cooldown", 0, "The time to wait after a resize, or the start of the nanny, before shrinking the resources. Disabled by default.")
+	growOnlyHours     = flag.String("grow-only-hours", "", "A range of hours of the day in local time, like 8-20, during which the resources are only grown. Shrinking is postponed until the end of the range.")
 )
 
 func main() {
@@ -56,6 +76,26 @@ func main() {
 	log.Infof("Invoked by %v", os.Args)
 	flag.Parse()
 
+	policy := nanny.ResizePolicy{
+		ScaleUpCooldown:   *scaleUpCooldown,
+		ScaleDownCooldown: *scaleDownCooldown,
+	}
+	if *growOnlyHours != "" {
+		hours, err := nanny.ParseDailyHours(*growOnlyHours)
+		if err != nil {
+			log.Fatal(err)
+		}
+		policy.GrowOnlyHours = hours
+	}
+	if *scaleUpCooldown < 0 || *scaleDownCooldown < 0 {
+		log.Fatal("Cooldowns must not be negative.")
+	}
+
+	if *configFile != "" {
+		pollTargets(policy)
+		return
+	}
+
 	// Perform further validation of flags.
 	if *deployment == "" {
 		log.Fatal("Must specify a deployment.")
@@ -65,8 +105,13 @@ func main() {
 		log.Fatalf("Threshold must be between 0 and 100 inclusively, was %d.", threshold)
 	}
 
+	validateLimit("cpu", *cpuLimit, *cpuLimitPerNode, *cpuLimitRatio)
+	validateLimit("memory", *memoryLimit, *memoryLimitPerNode, *memoryLimitRatio)
+	validateLimit("storage", *storageLimit, *storageLimitPerNode, *storageLimitRatio)
+
 	log.Infof("Watching namespace: %s, pod: %s, container: %s.", *podNamespace, *podName, *containerName)
-	log.Infof("cpu: %s, extra_cpu: %s, memory: %s, extra_memory: %s, storage: %s, extra_storage: %s", *baseCPU, *cpuPerNode, *baseMemory, *memoryPerNode, *baseStorage, *storagePerNode)
+	log.Infof("cpu: %s, extra_cpu: %s per %s, memory: %s, extra_memory: %s per %s, storage: %s, extra_storage: %s per %s",
+		*baseCPU, *cpuPerNode, *cpuDimension, *baseMemory, *memoryPerNode, *memoryDimension, *baseStorage, *storagePerNode, *storageDimension)
 
 	// Set up work objects.
 	config, err := restclient.InClusterConfig()
@@ -78,7 +123,9 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	k8s := nanny.NewKubernetesClient(*podNamespace, *deployment, *podName, *containerName, clientset)
+	nodes := nanny.NewNodeWatcher(clientset)
+	go nodes.Run()
+	k8s := nanny.NewKubernetesClient(*podNamespace, *deployment, *podName, *containerName, clientset, nodes)
 
 	var resources []nanny.Resource
 
@@ -88,6 +135,11 @@ func main() {
 			Base:         resource.MustParse(*baseCPU),
 			ExtraPerNode: resource.MustParse(*cpuPerNode),
 			Name:         "cpu",
+			Dimension:    mustParseDimension(*cpuDimension),
+
+			LimitBase:         parseLimit(*cpuLimit),
+			LimitExtraPerNode: resource.MustParse(*cpuLimitPerNode),
+			LimitRatio:        *cpuLimitRatio,
 		})
 	}
 
@@ -96,6 +148,11 @@ func main() {
 			Base:         resource.MustParse(*baseMemory),
 			ExtraPerNode: resource.MustParse(*memoryPerNode),
 			Name:         "memory",
+			Dimension:    mustParseDimension(*memoryDimension),
+
+			LimitBase:         parseLimit(*memoryLimit),
+			LimitExtraPerNode: resource.MustParse(*memoryLimitPerNode),
+			LimitRatio:        *memoryLimitRatio,
 		})
 	}
 
@@ -104,6 +161,11 @@ func main() {
 			Base:         resource.MustParse(*baseStorage),
 			ExtraPerNode: resource.MustParse(*memoryPerNode),
 			Name:         "storage",
+			Dimension:    mustParseDimension(*storageDimension),
+
+			LimitBase:         parseLimit(*storageLimit),
+			LimitExtraPerNode: resource.MustParse(*storageLimitPerNode),
+			LimitRatio:        *storageLimitRatio,
 		})
 	}
 
@@ -119,10 +181,101 @@ func main() {
 			Resources:   resources,
 			ScaleFactor: 1.5,
 		}
+	} else if *estimator == "step" {
+		if *stepTable == "" {
+			log.Fatal("Must specify a step table for the step estimator.")
+		}
+		stepEstimator, err := nanny.LoadStepEstimator(*stepTable)
+		if err != nil {
+			log.Fatalf("Failed to load step table: %v", err)
+		}
+		go stepEstimator.WatchFile(pollPeriod)
+		est = stepEstimator
 	} else {
 		log.Fatalf("Estimator %s not supported", *estimator)
 	}
 
 	// Begin nannying.
-	nanny.PollAPIServer(k8s, est, *containerName, pollPeriod, uint64(*threshold))
+	nanny.PollAPIServer(k8s, est, *containerName, pollPeriod, uint64(*threshold), policy)
+}
+
+// pollTargets nannies all the targets listed in the config file, with the
+// policy used for the settings missing in the targets.
+func pollTargets(policy nanny.ResizePolicy) {
+	file, err := os.Open(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	targetsConfig, err := nanny.LoadTargetsConfig(file, *podNamespace)
+	file.Close()
+	if err != nil {
+		log.Fatalf("Failed to load targets from %s: %v", *configFile, err)
+	}
+
+	config, err := restclient.InClusterConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	clientset, err := client.NewForConfig(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	nodes := nanny.NewNodeWatcher(clientset)
+	go nodes.Run()
+
+	var targets []*nanny.TargetNanny
+	for _, target := range targetsConfig.Targets {
+		est, err := target.BuildEstimator()
+		if err != nil {
+			log.Fatalf("Failed to build the estimator of %s: %v", target.Name(), err)
+		}
+		targetPolicy, err := target.ResizePolicy(policy)
+		if err != nil {
+			log.Fatalf("Failed to build the resize policy of %s: %v", target.Name(), err)
+		}
+		log.Infof("Watching %s with the %s estimator, resources: %+v", target.Name(), target.Estimator, target.Resources)
+		targets = append(targets, &nanny.TargetNanny{
+			Name:      target.Name(),
+			Client:    nanny.NewKubernetesClient(target.Namespace, target.Deployment, "", target.Container, clientset, nodes),
+			Estimator: est,
+			Threshold: target.Threshold,
+			Policy:    targetPolicy,
+		})
+	}
+
+	// Begin nannying. The cluster is measured once per poll for all targets.
+	nanny.PollTargets(nanny.NewClusterSizer(clientset, nodes), targets, pollPeriod)
+}
+
+func mustParseDimension(name string) nanny.Dimension {
+	dimension, err := nanny.ParseDimension(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return dimension
+}
+
+// validateLimit checks that at most one way of computing the limit of the resource is used,
+// and that the extra limit per node is only given with the base limit.
+func validateLimit(name, limit, limitPerNode string, ratio float64) {
+	if ratio != 0 && ratio < 1 {
+		log.Fatalf("The %s limit ratio must be at least 1, was %f.", name, ratio)
+	}
+	if ratio != 0 && limit != noValue {
+		log.Fatalf("Only one of %s-limit and %s-limit-ratio can be set.", name, name)
+	}
+	perNode := resource.MustParse(limitPerNode)
+	if limit == noValue && !perNode.IsZero() {
+		log.Fatalf("extra-%s-limit can only be set with %s-limit.", name, name)
+	}
+}
+
+// parseLimit returns the base limit given by a flag, or nil if the flag isn't set.
+func parseLimit(limit string) *resource.Quantity {
+	if limit == noValue {
+		return nil
+	}
+	quantity := resource.MustParse(limit)
+	return &quantity
 }
@@ -21,6 +21,8 @@ and update a deployment based on that status.
 package nanny
 
 import (
+	"fmt"
+	"sync"
 	"time"
 
 	log "github.com/golang/glog"
@@ -47,69 +49,190 @@ func checkResource(threshold int64, actual, expected api.ResourceList, res api.R
 	return false
 }
 
+// checkResourceList determines whether any resource of a list needs to be over-written.
+func checkResourceList(threshold int64, actual, expected api.ResourceList) bool {
+	return checkResource(threshold, actual, expected, api.ResourceCPU) ||
+		checkResource(threshold, actual, expected, api.ResourceMemory) ||
+		checkResource(threshold, actual, expected, api.ResourceStorage)
+}
+
 // shouldOverwriteResources determines if we should over-write the container's
-// resource limits. We'll over-write the resource limits if the limited
-// resources are different, or if any limit is violated by a threshold.
+// resources. Limits and requests are compared independently, as they may have
+// different expected values. We'll over-write the resources if the limited or
+// requested resources are different, or if any of them is violated by a threshold.
 func shouldOverwriteResources(threshold int64, limits, reqs, expLimits, expReqs api.ResourceList) bool {
-	return checkResource(threshold, limits, expLimits, api.ResourceCPU) ||
-		checkResource(threshold, limits, expLimits, api.ResourceMemory) ||
-		checkResource(threshold, limits, expLimits, api.ResourceStorage) ||
-		checkResource(threshold, reqs, expReqs, api.ResourceCPU) ||
-		checkResource(threshold, reqs, expReqs, api.ResourceMemory) ||
-		checkResource(threshold, reqs, expReqs, api.ResourceStorage)
+	return checkResourceList(threshold, limits, expLimits) || checkResourceList(threshold, reqs, expReqs)
+}
+
+// ClusterSizer measures the size of the cluster along the given dimensions.
+type ClusterSizer interface {
+	ClusterSize(dimensions []Dimension) (ClusterSize, error)
 }
 
 // KubernetesClient is an object that performs the nanny's requisite interactions with Kubernetes.
 type KubernetesClient interface {
-	CountNodes() (uint64, error)
+	ClusterSizer
 	ContainerResources() (*api.ResourceRequirements, error)
 	UpdateDeployment(resources *api.ResourceRequirements) error
 }
 
 // ResourceEstimator estimates ResourceRequirements for a given criteria.
 type ResourceEstimator interface {
-	scaleWithNodes(numNodes uint64) *api.ResourceRequirements
+	scaleWithClusterSize(size ClusterSize) *api.ResourceRequirements
+	// dimensions returns the dimensions of the cluster size used by the estimator.
+	dimensions() []Dimension
+	// snapshot returns an estimator that doesn't change while it is used, even if
+	// this one is reconfigured, so its dimensions and estimates stay consistent.
+	snapshot() ResourceEstimator
 }
 
-// PollAPIServer periodically counts the number of nodes, estimates the expected
+// PollAPIServer periodically measures the cluster size, estimates the expected
 // ResourceRequirements, compares them to the actual ResourceRequirements, and
-// updates the deployment with the expected ResourceRequirements if necessary.
-func PollAPIServer(k8s KubernetesClient, est ResourceEstimator, contName string, pollPeriod time.Duration, threshold uint64) {
+// updates the deployment with the expected ResourceRequirements if necessary
+// and allowed by the policy.
+func PollAPIServer(k8s KubernetesClient, est ResourceEstimator, contName string, pollPeriod time.Duration, threshold uint64, policy ResizePolicy) {
+	pollTarget(&TargetNanny{
+		Name:      contName,
+		Client:    k8s,
+		Estimator: est,
+		Threshold: threshold,
+		Policy:    policy,
+	}, pollPeriod)
+}
+
+// TargetNanny resizes the container of a single target deployment.
+type TargetNanny struct {
+	// Name identifies the target in the logs.
+	Name      string
+	Client    KubernetesClient
+	Estimator ResourceEstimator
+	Threshold uint64
+	Policy    ResizePolicy
+}
+
+// PollTargets periodically reconciles all targets. The cluster is measured
+// once per poll by the sizer, along the dimensions of all the estimators, and
+// the targets are then reconciled concurrently against the same size. Errors
+// and panics while reconciling a target are logged and don't affect the other
+// targets.
+func PollTargets(sizer ClusterSizer, targets []*TargetNanny, pollPeriod time.Duration) {
+	// Unlike the sidecar, this nanny doesn't restart together with the targets,
+	// so their first resize doesn't wait for the cooldowns.
 	for i := 0; true; i++ {
 		if i != 0 {
 			// Sleep for the poll period.
 			time.Sleep(pollPeriod)
 		}
-
-		// Query the apiserver for the number of nodes.
-		num, err := k8s.CountNodes()
-		if err != nil {
+		if err := reconcileTargets(sizer, targets, time.Now()); err != nil {
 			log.Error(err)
-			continue
 		}
-		log.Infof("The number of nodes is %d", num)
+	}
+}
 
-		// Query the apiserver for this pod's information.
-		resources, err := k8s.ContainerResources()
-		if err != nil {
-			log.Errorf("Error while querying apiserver for resources: %v", err)
-			continue
+// reconcileTargets measures the cluster once and reconciles all targets
+// against that size at the given time.
+func reconcileTargets(sizer ClusterSizer, targets []*TargetNanny, now time.Time) error {
+	// The estimators may be reconfigured concurrently, use the same configuration
+	// to measure the cluster and to estimate the resources.
+	estimators := make([]ResourceEstimator, len(targets))
+	var dimensions []Dimension
+	seen := make(map[Dimension]bool)
+	for i, target := range targets {
+		estimators[i] = target.Estimator.snapshot()
+		for _, dimension := range estimators[i].dimensions() {
+			if !seen[dimension] {
+				seen[dimension] = true
+				dimensions = append(dimensions, dimension)
+			}
 		}
-		log.Infof("The container resources are %+v", *resources)
+	}
 
-		// Get the expected resource limits.
-		expResources := est.scaleWithNodes(num)
-		log.Infof("The expected resources are %+v", *expResources)
+	// Query the apiserver for the cluster size.
+	size, err := sizer.ClusterSize(dimensions)
+	if err != nil {
+		return fmt.Errorf("Error while measuring the cluster: %v", err)
+	}
+	log.Infof("The cluster size is %+v", size)
 
-		// If there's a difference, go ahead and set the new values.
-		if !shouldOverwriteResources(int64(threshold), resources.Limits, resources.Requests, expResources.Limits, expResources.Requests) {
-			log.Infof("Resources are within the expected limits.")
-			continue
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(target *TargetNanny, estimator ResourceEstimator) {
+			defer wg.Done()
+			if err := reconcileTargetWithSize(target, estimator, size, now); err != nil {
+				log.Errorf("%s: %v", target.Name, err)
+			}
+		}(target, estimators[i])
+	}
+	wg.Wait()
+	return nil
+}
+
+func pollTarget(target *TargetNanny, pollPeriod time.Duration) {
+	target.Policy.lastResize = time.Now()
+	for i := 0; true; i++ {
+		if i != 0 {
+			// Sleep for the poll period.
+			time.Sleep(pollPeriod)
 		}
-		log.Infof("Resources are not within the expected limits: updating the deployment.")
-		if err := k8s.UpdateDeployment(expResources); err != nil {
-			log.Error(err)
-			continue
+		if err := reconcileTarget(target, time.Now()); err != nil {
+			log.Errorf("%s: %v", target.Name, err)
 		}
 	}
 }
+
+// reconcileTarget measures the cluster size, estimates the expected
+// ResourceRequirements of the target, compares them to the actual
+// ResourceRequirements, and updates the deployment if necessary and allowed
+// by the policy at the given time.
+func reconcileTarget(target *TargetNanny, now time.Time) error {
+	// The estimator may be reconfigured concurrently, use the same configuration
+	// to measure the cluster and to estimate the resources.
+	estimator := target.Estimator.snapshot()
+
+	// Query the apiserver for the cluster size.
+	size, err := target.Client.ClusterSize(estimator.dimensions())
+	if err != nil {
+		return err
+	}
+	log.Infof("%s: The cluster size is %+v", target.Name, size)
+	return reconcileTargetWithSize(target, estimator, size, now)
+}
+
+// reconcileTargetWithSize reconciles the target like reconcileTarget, with
+// the cluster already measured and the estimator already snapshotted.
+func reconcileTargetWithSize(target *TargetNanny, estimator ResourceEstimator, size ClusterSize, now time.Time) (err error) {
+	// A panic while reconciling one target must not stop the others.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Panic while reconciling: %v", r)
+		}
+	}()
+
+	// Query the apiserver for the container's information.
+	resources, err := target.Client.ContainerResources()
+	if err != nil {
+		return fmt.Errorf("Error while querying apiserver for resources: %v", err)
+	}
+	log.Infof("%s: The container resources are %+v", target.Name, *resources)
+
+	// Get the expected resource limits.
+	expResources := estimator.scaleWithClusterSize(size)
+	log.Infof("%s: The expected resources are %+v", target.Name, *expResources)
+
+	// If there's a difference, go ahead and set the new values.
+	if !shouldOverwriteResources(int64(target.Threshold), resources.Limits, resources.Requests, expResources.Limits, expResources.Requests) {
+		log.Infof("%s: Resources are within the expected limits.", target.Name)
+		return nil
+	}
+	if allowed, reason := target.Policy.allowResize(now, isScaleUp(resources, expResources)); !allowed {
+		log.Infof("%s: Resources are not within the expected limits, but the resize is postponed: %s.", target.Name, reason)
+		return nil
+	}
+	log.Infof("%s: Resources are not within the expected limits: updating the deployment.", target.Name)
+	if err := target.Client.UpdateDeployment(expResources); err != nil {
+		return err
+	}
+	target.Policy.lastResize = now
+	return nil
+}
@@ -17,7 +17,9 @@ limitations under the License.
 package nanny
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	resource "k8s.io/kubernetes/pkg/api/resource"
 	api "k8s.io/kubernetes/pkg/api/v1"
@@ -189,3 +191,188 @@ func TestShouldOverwriteResources(t *testing.T) {
 		}
 	}
 }
+
+func TestShouldOverwriteLimitsAndRequestsIndependently(t *testing.T) {
+	testCases := []struct {
+		th                               int64
+		limits, reqs, expLimits, expReqs api.ResourceList
+		want                             bool
+	}{
+		// Different expected limits and requests.
+		{0, standard, smallCPU, standard, smallCPU, false},
+		{10, siStandard, smallCPU, standard, smallCPU, false},
+		// Only the limits differ.
+		{0, standard, smallCPU, smallMemory, smallCPU, true},
+		{0, standard, smallCPU, noStorage, smallCPU, true},
+		// Only the requests differ.
+		{0, standard, smallCPU, standard, smallMemory, true},
+		{10, standard, smallCPU, standard, standard, true},
+		// Limits and requests are swapped.
+		{0, smallCPU, standard, standard, smallCPU, true},
+	}
+	for i, tc := range testCases {
+		if tc.want != shouldOverwriteResources(tc.th, tc.limits, tc.reqs, tc.expLimits, tc.expReqs) {
+			t.Errorf("shouldOverwriteResources got %t, want %t for test case %d.", !tc.want, tc.want, i)
+		}
+	}
+}
+
+type fakeKubernetesClient struct {
+	size      ClusterSize
+	sizeErr   error
+	resources *api.ResourceRequirements
+	updated   *api.ResourceRequirements
+}
+
+func (f *fakeKubernetesClient) ClusterSize(dimensions []Dimension) (ClusterSize, error) {
+	return f.size, f.sizeErr
+}
+
+func (f *fakeKubernetesClient) ContainerResources() (*api.ResourceRequirements, error) {
+	return f.resources, nil
+}
+
+func (f *fakeKubernetesClient) UpdateDeployment(resources *api.ResourceRequirements) error {
+	f.updated = resources
+	return nil
+}
+
+type panickingEstimator struct{}
+
+func (panickingEstimator) scaleWithClusterSize(size ClusterSize) *api.ResourceRequirements {
+	panic("estimator failure")
+}
+
+func (panickingEstimator) dimensions() []Dimension {
+	return []Dimension{NodesDimension}
+}
+
+func (e panickingEstimator) snapshot() ResourceEstimator {
+	return e
+}
+
+func TestReconcileTarget(t *testing.T) {
+	est := LinearEstimator{Resources: []Resource{
+		{Base: resource.MustParse("0.3"), ExtraPerNode: resource.MustParse("0"), Name: "cpu"},
+		{Base: resource.MustParse("100Mi"), ExtraPerNode: resource.MustParse("50Mi"), Name: "memory"},
+	}}
+	upToDate := &api.ResourceRequirements{Limits: noStorage, Requests: noStorage}
+	outdated := &api.ResourceRequirements{Limits: smallMemoryNoStorage, Requests: smallMemoryNoStorage}
+
+	testCases := []struct {
+		client     *fakeKubernetesClient
+		est        ResourceEstimator
+		wantErr    bool
+		wantUpdate bool
+	}{
+		{&fakeKubernetesClient{size: ClusterSize{Nodes: 2}, resources: upToDate}, est, false, false},
+		{&fakeKubernetesClient{size: ClusterSize{Nodes: 2}, resources: outdated}, est, false, true},
+		{&fakeKubernetesClient{sizeErr: fmt.Errorf("apiserver down")}, est, true, false},
+		// A panic is returned as an error.
+		{&fakeKubernetesClient{size: ClusterSize{Nodes: 2}, resources: outdated}, panickingEstimator{}, true, false},
+	}
+	for i, tc := range testCases {
+		err := reconcileTarget(&TargetNanny{Name: "test", Client: tc.client, Estimator: tc.est}, time.Now())
+		if (err != nil) != tc.wantErr {
+			t.Errorf("reconcileTarget got error %v, want error %t for test case %d.", err, tc.wantErr, i)
+		}
+		if (tc.client.updated != nil) != tc.wantUpdate {
+			t.Errorf("reconcileTarget updated %+v, want update %t for test case %d.", tc.client.updated, tc.wantUpdate, i)
+		}
+		if tc.wantUpdate {
+			verifyResources(t, "requests", tc.client.updated.Requests, noStorage)
+		}
+	}
+}
+
+func TestReconcileTargetCooldown(t *testing.T) {
+	est := LinearEstimator{Resources: []Resource{
+		{Base: resource.MustParse("0.3"), ExtraPerNode: resource.MustParse("0"), Name: "cpu"},
+		{Base: resource.MustParse("100Mi"), ExtraPerNode: resource.MustParse("50Mi"), Name: "memory"},
+	}}
+	client := &fakeKubernetesClient{
+		size:      ClusterSize{Nodes: 2},
+		resources: &api.ResourceRequirements{Limits: smallMemoryNoStorage, Requests: smallMemoryNoStorage},
+	}
+	start := time.Now()
+	target := &TargetNanny{
+		Name:      "test",
+		Client:    client,
+		Estimator: est,
+		Policy:    ResizePolicy{ScaleUpCooldown: time.Minute, ScaleDownCooldown: 10 * time.Minute, lastResize: start},
+	}
+
+	// The scale up waits for the cooldown.
+	if err := reconcileTarget(target, start.Add(30*time.Second)); err != nil || client.updated != nil {
+		t.Fatalf("reconcileTarget got %v and updated %+v during the scale up cooldown", err, client.updated)
+	}
+	if err := reconcileTarget(target, start.Add(time.Minute)); err != nil || client.updated == nil {
+		t.Fatalf("reconcileTarget got %v and didn't update after the scale up cooldown", err)
+	}
+
+	// The following scale down waits for the longer cooldown since the scale up.
+	client.resources, client.updated = client.updated, nil
+	client.size = ClusterSize{Nodes: 1}
+	if err := reconcileTarget(target, start.Add(5*time.Minute)); err != nil || client.updated != nil {
+		t.Fatalf("reconcileTarget got %v and updated %+v during the scale down cooldown", err, client.updated)
+	}
+	if err := reconcileTarget(target, start.Add(11*time.Minute)); err != nil || client.updated == nil {
+		t.Fatalf("reconcileTarget got %v and didn't update after the scale down cooldown", err)
+	}
+	verifyResources(t, "requests", client.updated.Requests, api.ResourceList{
+		"cpu":    resource.MustParse("0.3"),
+		"memory": resource.MustParse("150Mi"),
+	})
+}
+
+type countingClusterSizer struct {
+	size       ClusterSize
+	calls      int
+	dimensions []Dimension
+}
+
+func (c *countingClusterSizer) ClusterSize(dimensions []Dimension) (ClusterSize, error) {
+	c.calls++
+	c.dimensions = dimensions
+	return c.size, nil
+}
+
+func TestReconcileTargets(t *testing.T) {
+	linear := LinearEstimator{Resources: []Resource{
+		{Base: resource.MustParse("0.3"), ExtraPerNode: resource.MustParse("0"), Name: "cpu"},
+		{Base: resource.MustParse("100Mi"), ExtraPerNode: resource.MustParse("50Mi"), Name: "memory"},
+	}}
+	pods := LinearEstimator{Resources: []Resource{
+		{Base: resource.MustParse("0.3"), ExtraPerNode: resource.MustParse("0"), Name: "cpu"},
+		{Base: resource.MustParse("100Mi"), ExtraPerNode: resource.MustParse("10Mi"), Name: "memory", Dimension: PodsDimension},
+	}}
+	outdated := &api.ResourceRequirements{Limits: smallMemoryNoStorage, Requests: smallMemoryNoStorage}
+	// The targets' clients fail if they are asked to measure the cluster.
+	first := &fakeKubernetesClient{sizeErr: fmt.Errorf("measured by the target"), resources: outdated}
+	second := &fakeKubernetesClient{sizeErr: fmt.Errorf("measured by the target"), resources: outdated}
+	sizer := &countingClusterSizer{size: ClusterSize{Nodes: 2, Pods: 5}}
+	// The targets were never resized, so the cooldowns don't apply.
+	policy := ResizePolicy{ScaleUpCooldown: time.Minute, ScaleDownCooldown: 10 * time.Minute}
+	targets := []*TargetNanny{
+		{Name: "first", Client: first, Estimator: linear, Policy: policy},
+		{Name: "second", Client: second, Estimator: pods, Policy: policy},
+	}
+
+	if err := reconcileTargets(sizer, targets, time.Now()); err != nil {
+		t.Fatalf("reconcileTargets got error %v", err)
+	}
+	if sizer.calls != 1 {
+		t.Errorf("reconcileTargets measured the cluster %d times, want 1", sizer.calls)
+	}
+	if len(sizer.dimensions) != 2 || sizer.dimensions[0] != NodesDimension || sizer.dimensions[1] != PodsDimension {
+		t.Errorf("reconcileTargets measured %v, want [%s %s]", sizer.dimensions, NodesDimension, PodsDimension)
+	}
+	if first.updated == nil || second.updated == nil {
+		t.Fatalf("reconcileTargets updated %+v and %+v, want both targets updated", first.updated, second.updated)
+	}
+	verifyResources(t, "requests", first.updated.Requests, noStorage)
+	verifyResources(t, "requests", second.updated.Requests, api.ResourceList{
+		"cpu":    resource.MustParse("0.3"),
+		"memory": resource.MustParse("150Mi"),
+	})
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nanny
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/golang/glog"
+	api "k8s.io/kubernetes/pkg/api"
+	apiv1 "k8s.io/kubernetes/pkg/api/v1"
+	client "k8s.io/kubernetes/pkg/client/clientset_generated/release_1_3"
+	"k8s.io/kubernetes/pkg/watch"
+)
+
+// How long to wait before listing the nodes again after the watch failed.
+const nodeWatchRetryPeriod = 5 * time.Second
+
+// NodeWatcher keeps track of the nodes of the cluster by listing them once and
+// then watching for changes, so that they don't need to be listed on every poll.
+type NodeWatcher struct {
+	mutex  sync.Mutex
+	nodes  map[string]bool
+	synced bool
+
+	list  func() (*apiv1.NodeList, error)
+	watch func(resourceVersion string) (watch.Interface, error)
+}
+
+// NewNodeWatcher builds a NodeWatcher using the clientset. Run must be called
+// to start watching.
+func NewNodeWatcher(clientset *client.Clientset) *NodeWatcher {
+	return newNodeWatcher(
+		func() (*apiv1.NodeList, error) {
+			return clientset.CoreClient.Nodes().List(api.ListOptions{})
+		},
+		func(resourceVersion string) (watch.Interface, error) {
+			return clientset.CoreClient.Nodes().Watch(api.ListOptions{Watch: true, ResourceVersion: resourceVersion})
+		})
+}
+
+func newNodeWatcher(list func() (*apiv1.NodeList, error), watch func(resourceVersion string) (watch.Interface, error)) *NodeWatcher {
+	return &NodeWatcher{
+		nodes: make(map[string]bool),
+		list:  list,
+		watch: watch,
+	}
+}
+
+// Run lists and watches the nodes forever. The nodes are listed again whenever
+// the watch ends.
+func (w *NodeWatcher) Run() {
+	for {
+		if err := w.listAndWatch(); err != nil {
+			log.Errorf("Error while watching nodes: %v", err)
+			time.Sleep(nodeWatchRetryPeriod)
+		}
+	}
+}
+
+// Count returns the number of nodes, and false if the nodes weren't listed yet.
+func (w *NodeWatcher) Count() (uint64, bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return uint64(len(w.nodes)), w.synced
+}
+
+func (w *NodeWatcher) listAndWatch() error {
+	list, err := w.list()
+	if err != nil {
+		return err
+	}
+	nodes := make(map[string]bool)
+	for _, node := range list.Items {
+		nodes[node.Name] = true
+	}
+	w.mutex.Lock()
+	w.nodes = nodes
+	w.synced = true
+	w.mutex.Unlock()
+
+	watcher, err := w.watch(list.ResourceVersion)
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+	for event := range watcher.ResultChan() {
+		if event.Type == watch.Error {
+			return fmt.Errorf("Watch failed: %+v", event.Object)
+		}
+		node, ok := event.Object.(*apiv1.Node)
+		if !ok {
+			return fmt.Errorf("Unexpected object in node watch: %+v", event.Object)
+		}
+		w.mutex.Lock()
+		switch event.Type {
+		case watch.Added, watch.Modified:
+			w.nodes[node.Name] = true
+		case watch.Deleted:
+			delete(w.nodes, node.Name)
+		}
+		w.mutex.Unlock()
+	}
+	// The watch timed out, list the nodes again.
+	return nil
+}
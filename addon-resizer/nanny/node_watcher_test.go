@@ -0,0 +1,68 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nanny
+
+import (
+	"testing"
+
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	api "k8s.io/kubernetes/pkg/api/v1"
+	"k8s.io/kubernetes/pkg/watch"
+)
+
+func node(name string) *api.Node {
+	return &api.Node{ObjectMeta: api.ObjectMeta{Name: name}}
+}
+
+func TestNodeWatcher(t *testing.T) {
+	fakeWatch := watch.NewFake()
+	var watchedVersion string
+	w := newNodeWatcher(
+		func() (*api.NodeList, error) {
+			return &api.NodeList{
+				ListMeta: unversioned.ListMeta{ResourceVersion: "10"},
+				Items:    []api.Node{*node("n1"), *node("n2")},
+			}, nil
+		},
+		func(resourceVersion string) (watch.Interface, error) {
+			watchedVersion = resourceVersion
+			return fakeWatch, nil
+		})
+
+	if _, synced := w.Count(); synced {
+		t.Errorf("Count got synced before listing the nodes")
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- w.listAndWatch()
+	}()
+	fakeWatch.Add(node("n3"))
+	fakeWatch.Delete(node("n1"))
+	fakeWatch.Modify(node("n2"))
+	fakeWatch.Stop()
+	if err := <-done; err != nil {
+		t.Errorf("listAndWatch failed: %v", err)
+	}
+
+	if watchedVersion != "10" {
+		t.Errorf("watch started from version %s, want 10", watchedVersion)
+	}
+	if count, synced := w.Count(); count != 2 || !synced {
+		t.Errorf("Count got %d, %t, want 2, true", count, synced)
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nanny
+
+import (
+	"fmt"
+	"time"
+
+	api "k8s.io/kubernetes/pkg/api/v1"
+)
+
+// DailyHours is a range of hours of the day in local time, from Start
+// inclusively to End exclusively. The range wraps around midnight if End is
+// lower than Start.
+type DailyHours struct {
+	Start, End int
+}
+
+// ParseDailyHours parses a range of hours like "8-20".
+func ParseDailyHours(value string) (*DailyHours, error) {
+	hours := &DailyHours{}
+	if _, err := fmt.Sscanf(value, "%d-%d", &hours.Start, &hours.End); err != nil {
+		return nil, fmt.Errorf("Invalid hours %s, expected a range like 8-20: %v", value, err)
+	}
+	if hours.Start < 0 || hours.Start > 23 || hours.End < 0 || hours.End > 24 || hours.Start == hours.End {
+		return nil, fmt.Errorf("Invalid hours %s, expected a non-empty range of hours between 0 and 24.", value)
+	}
+	return hours, nil
+}
+
+// contains checks whether the time is within the hours.
+func (h *DailyHours) contains(t time.Time) bool {
+	hour := t.Hour()
+	if h.Start < h.End {
+		return h.Start <= hour && hour < h.End
+	}
+	return hour >= h.Start || hour < h.End
+}
+
+// ResizePolicy limits how often the resources of a container are changed, to
+// prevent restarting it over and over when the cluster size hovers around the
+// threshold. A resize that grows any of the resources must wait ScaleUpCooldown
+// since the previous resize, any other resize must wait ScaleDownCooldown.
+// During GrowOnlyHours, if set, only resizes that grow resources are allowed.
+type ResizePolicy struct {
+	ScaleUpCooldown   time.Duration
+	ScaleDownCooldown time.Duration
+	GrowOnlyHours     *DailyHours
+
+	// The time of the last resize. PollAPIServer sets it to the start of the
+	// nanny, which runs as a sidecar and restarts together with the container
+	// it resizes. PollTargets leaves it zero, as it runs apart from the targets.
+	lastResize time.Time
+}
+
+// allowResize checks whether the resources can be changed at the given time,
+// giving the reason if they can't.
+func (p *ResizePolicy) allowResize(now time.Time, scaleUp bool) (bool, string) {
+	if !scaleUp && p.GrowOnlyHours != nil && p.GrowOnlyHours.contains(now) {
+		return false, fmt.Sprintf("resources don't shrink between %d:00 and %d:00", p.GrowOnlyHours.Start, p.GrowOnlyHours.End)
+	}
+	cooldown := p.ScaleDownCooldown
+	if scaleUp {
+		cooldown = p.ScaleUpCooldown
+	}
+	if since := now.Sub(p.lastResize); since < cooldown {
+		return false, fmt.Sprintf("the last resize was %v ago, the cooldown is %v", since, cooldown)
+	}
+	return true, ""
+}
+
+// isScaleUp checks whether any of the expected resources is higher than the
+// actual one, or missing in the actual resources.
+func isScaleUp(actual, expected *api.ResourceRequirements) bool {
+	return growsResourceList(actual.Limits, expected.Limits) || growsResourceList(actual.Requests, expected.Requests)
+}
+
+func growsResourceList(actual, expected api.ResourceList) bool {
+	for name, expQuantity := range expected {
+		quantity, found := actual[name]
+		if !found || expQuantity.Cmp(quantity) > 0 {
+			return true
+		}
+	}
+	return false
+}
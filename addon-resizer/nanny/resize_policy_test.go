@@ -0,0 +1,127 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nanny
+
+import (
+	"testing"
+	"time"
+
+	api "k8s.io/kubernetes/pkg/api/v1"
+)
+
+func TestParseDailyHours(t *testing.T) {
+	testCases := []struct {
+		value string
+		want  *DailyHours
+	}{
+		{"8-20", &DailyHours{8, 20}},
+		{"22-6", &DailyHours{22, 6}},
+		{"0-24", &DailyHours{0, 24}},
+		{"8", nil},
+		{"day", nil},
+		{"8-8", nil},
+		{"8-25", nil},
+		{"-1-8", nil},
+	}
+	for _, tc := range testCases {
+		got, err := ParseDailyHours(tc.value)
+		if tc.want == nil {
+			if err == nil {
+				t.Errorf("ParseDailyHours(%s) got %+v, want error", tc.value, got)
+			}
+			continue
+		}
+		if err != nil || *got != *tc.want {
+			t.Errorf("ParseDailyHours(%s) got %+v, %v, want %+v", tc.value, got, err, tc.want)
+		}
+	}
+}
+
+func TestDailyHoursContains(t *testing.T) {
+	testCases := []struct {
+		hours DailyHours
+		hour  int
+		want  bool
+	}{
+		{DailyHours{8, 20}, 7, false},
+		{DailyHours{8, 20}, 8, true},
+		{DailyHours{8, 20}, 19, true},
+		{DailyHours{8, 20}, 20, false},
+		// The range wraps around midnight.
+		{DailyHours{22, 6}, 21, false},
+		{DailyHours{22, 6}, 23, true},
+		{DailyHours{22, 6}, 0, true},
+		{DailyHours{22, 6}, 6, false},
+	}
+	for _, tc := range testCases {
+		now := time.Date(2016, 8, 1, tc.hour, 30, 0, 0, time.Local)
+		if got := tc.hours.contains(now); got != tc.want {
+			t.Errorf("%+v contains %d:30 got %t, want %t", tc.hours, tc.hour, got, tc.want)
+		}
+	}
+}
+
+func TestAllowResize(t *testing.T) {
+	lastResize := time.Date(2016, 8, 1, 21, 0, 0, 0, time.Local)
+	policy := ResizePolicy{
+		ScaleUpCooldown:   time.Minute,
+		ScaleDownCooldown: 10 * time.Minute,
+		GrowOnlyHours:     &DailyHours{8, 20},
+		lastResize:        lastResize,
+	}
+	testCases := []struct {
+		now     time.Time
+		scaleUp bool
+		want    bool
+	}{
+		{lastResize.Add(30 * time.Second), true, false},
+		{lastResize.Add(time.Minute), true, true},
+		{lastResize.Add(5 * time.Minute), false, false},
+		{lastResize.Add(10 * time.Minute), false, true},
+		// Only scale ups are allowed during the grow-only hours.
+		{lastResize.Add(12 * time.Hour), true, true},
+		{lastResize.Add(12 * time.Hour), false, false},
+		{lastResize.Add(24 * time.Hour), false, true},
+	}
+	for i, tc := range testCases {
+		if got, reason := policy.allowResize(tc.now, tc.scaleUp); got != tc.want {
+			t.Errorf("allowResize got %t (%s), want %t for test case %d.", got, reason, tc.want, i)
+		}
+	}
+}
+
+func TestIsScaleUp(t *testing.T) {
+	testCases := []struct {
+		actual, expected api.ResourceList
+		want             bool
+	}{
+		{standard, standard, false},
+		{standard, smallCPU, false},
+		{smallCPU, standard, true},
+		{noStorage, standard, true},
+		{standard, noStorage, false},
+		// A resource grows while another shrinks.
+		{smallCPU, smallMemory, true},
+	}
+	for i, tc := range testCases {
+		actual := &api.ResourceRequirements{Limits: tc.actual, Requests: tc.actual}
+		expected := &api.ResourceRequirements{Limits: tc.expected, Requests: tc.expected}
+		if got := isScaleUp(actual, expected); got != tc.want {
+			t.Errorf("isScaleUp got %t, want %t for test case %d.", got, tc.want, i)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nanny
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/golang/glog"
+	api "k8s.io/kubernetes/pkg/api/v1"
+	"k8s.io/kubernetes/pkg/util/yaml"
+)
+
+// Step is a row of a StepTable: the resources used from the given cluster size up.
+// Resources missing in Limits have the limit equal to the request.
+type Step struct {
+	Size     uint64           `json:"size"`
+	Requests api.ResourceList `json:"requests"`
+	Limits   api.ResourceList `json:"limits,omitempty"`
+}
+
+// StepTable is the table of a StepEstimator. The cluster size is measured along
+// Dimension, which defaults to nodes.
+type StepTable struct {
+	Dimension Dimension `json:"dimension,omitempty"`
+	Steps     []Step    `json:"steps"`
+}
+
+// LoadStepTable reads a StepTable from a YAML or JSON stream, for example a file
+// mounted from a ConfigMap, and validates it. The steps are sorted by size.
+func LoadStepTable(reader io.Reader) (*StepTable, error) {
+	table := &StepTable{}
+	if err := yaml.NewYAMLOrJSONDecoder(reader, 4096).Decode(table); err != nil {
+		return nil, fmt.Errorf("Failed to decode step table: %v", err)
+	}
+	if err := table.validate(); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// validate checks the table, defaulting the dimension and sorting the steps by size.
+func (table *StepTable) validate() error {
+	if table.Dimension == "" {
+		table.Dimension = NodesDimension
+	}
+	if _, err := ParseDimension(string(table.Dimension)); err != nil {
+		return err
+	}
+	if len(table.Steps) == 0 {
+		return fmt.Errorf("Step table has no steps.")
+	}
+	sort.Sort(stepsBySize(table.Steps))
+	for i, step := range table.Steps {
+		if i > 0 && table.Steps[i-1].Size == step.Size {
+			return fmt.Errorf("Step table has more than one step for size %d.", step.Size)
+		}
+		for name, limit := range step.Limits {
+			request, found := step.Requests[name]
+			if found && limit.Cmp(request) < 0 {
+				return fmt.Errorf("The %s limit of the step for size %d is lower than the request.", name, step.Size)
+			}
+		}
+	}
+	return nil
+}
+
+// StepEstimator estimates the amount of resources from a table of cluster size
+// breakpoints. The step with the highest size at or below the current cluster
+// size is used, or the first step if the cluster is smaller than all of them.
+// The table can be replaced while the estimator is in use.
+type StepEstimator struct {
+	mutex sync.Mutex
+	table *StepTable
+
+	// The file the table is loaded from and its last content.
+	path        string
+	lastContent []byte
+}
+
+// NewStepEstimator builds a StepEstimator with the given table.
+func NewStepEstimator(table *StepTable) *StepEstimator {
+	return &StepEstimator{table: table}
+}
+
+// LoadStepEstimator builds a StepEstimator with the table loaded from the file.
+func LoadStepEstimator(path string) (*StepEstimator, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	table, err := LoadStepTable(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	return &StepEstimator{
+		table:       table,
+		path:        path,
+		lastContent: content,
+	}, nil
+}
+
+// SetTable replaces the table of the estimator.
+func (e *StepEstimator) SetTable(table *StepTable) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.table = table
+}
+
+// WatchFile reloads the table from the file it was loaded from whenever the
+// file content changes, checking every period. A ConfigMap mounted as a volume
+// is updated in place, so the table can be tuned by editing the ConfigMap.
+func (e *StepEstimator) WatchFile(period time.Duration) {
+	for {
+		time.Sleep(period)
+		e.reloadFile()
+	}
+}
+
+// reloadFile loads the table from the file if its content changed. Invalid
+// tables are logged and the previous table stays in use.
+func (e *StepEstimator) reloadFile() {
+	content, err := ioutil.ReadFile(e.path)
+	if err != nil {
+		log.Errorf("Failed to read step table from %s: %v", e.path, err)
+		return
+	}
+	if bytes.Equal(content, e.lastContent) {
+		return
+	}
+	e.lastContent = content
+	table, err := LoadStepTable(bytes.NewReader(content))
+	if err != nil {
+		log.Errorf("Failed to load step table from %s: %v", e.path, err)
+		return
+	}
+	log.Infof("Loaded step table from %s: %+v", e.path, *table)
+	e.SetTable(table)
+}
+
+// snapshot returns an estimator with the current table. Tables are never
+// modified, only replaced, so the snapshot doesn't change.
+func (e *StepEstimator) snapshot() ResourceEstimator {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return &StepEstimator{table: e.table}
+}
+
+func (e *StepEstimator) scaleWithClusterSize(size ClusterSize) *api.ResourceRequirements {
+	e.mutex.Lock()
+	table := e.table
+	e.mutex.Unlock()
+
+	count := size.Get(table.Dimension)
+	step := table.Steps[0]
+	for _, s := range table.Steps {
+		if s.Size <= count {
+			step = s
+		}
+	}
+
+	limits := make(api.ResourceList)
+	requests := make(api.ResourceList)
+	for name, request := range step.Requests {
+		requests[name] = request
+		limits[name] = request
+	}
+	for name, limit := range step.Limits {
+		limits[name] = limit
+	}
+	return &api.ResourceRequirements{
+		Limits:   limits,
+		Requests: requests,
+	}
+}
+
+func (e *StepEstimator) dimensions() []Dimension {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return []Dimension{e.table.Dimension}
+}
+
+type stepsBySize []Step
+
+func (s stepsBySize) Len() int           { return len(s) }
+func (s stepsBySize) Less(i, j int) bool { return s[i].Size < s[j].Size }
+func (s stepsBySize) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,161 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nanny
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	resource "k8s.io/kubernetes/pkg/api/resource"
+	api "k8s.io/kubernetes/pkg/api/v1"
+)
+
+const stepTable = `
+steps:
+- size: 100
+  requests:
+    cpu: 200m
+    memory: 300Mi
+- size: 0
+  requests:
+    cpu: 100m
+    memory: 100Mi
+  limits:
+    memory: 200Mi
+- size: 10
+  requests:
+    cpu: 150m
+    memory: 200Mi
+`
+
+func TestStepEstimator(t *testing.T) {
+	table, err := LoadStepTable(strings.NewReader(stepTable))
+	if err != nil {
+		t.Fatalf("LoadStepTable failed: %v", err)
+	}
+	estimator := NewStepEstimator(table)
+	if dimensions := estimator.dimensions(); len(dimensions) != 1 || dimensions[0] != NodesDimension {
+		t.Errorf("dimensions got %v, want [nodes]", dimensions)
+	}
+
+	testCases := []struct {
+		numNodes uint64
+		limits   api.ResourceList
+		requests api.ResourceList
+	}{
+		{0, api.ResourceList{"cpu": resource.MustParse("100m"), "memory": resource.MustParse("200Mi")},
+			api.ResourceList{"cpu": resource.MustParse("100m"), "memory": resource.MustParse("100Mi")}},
+		{9, api.ResourceList{"cpu": resource.MustParse("100m"), "memory": resource.MustParse("200Mi")},
+			api.ResourceList{"cpu": resource.MustParse("100m"), "memory": resource.MustParse("100Mi")}},
+		{10, api.ResourceList{"cpu": resource.MustParse("150m"), "memory": resource.MustParse("200Mi")},
+			api.ResourceList{"cpu": resource.MustParse("150m"), "memory": resource.MustParse("200Mi")}},
+		{99, api.ResourceList{"cpu": resource.MustParse("150m"), "memory": resource.MustParse("200Mi")},
+			api.ResourceList{"cpu": resource.MustParse("150m"), "memory": resource.MustParse("200Mi")}},
+		{1000, api.ResourceList{"cpu": resource.MustParse("200m"), "memory": resource.MustParse("300Mi")},
+			api.ResourceList{"cpu": resource.MustParse("200m"), "memory": resource.MustParse("300Mi")}},
+	}
+	for _, tc := range testCases {
+		got := estimator.scaleWithClusterSize(ClusterSize{Nodes: tc.numNodes})
+		verifyResources(t, "limits", got.Limits, tc.limits)
+		verifyResources(t, "requests", got.Requests, tc.requests)
+	}
+
+	// The first step is used below the lowest size.
+	table, err = LoadStepTable(strings.NewReader(`{"dimension": "pods", "steps": [{"size": 50, "requests": {"cpu": "1"}}]}`))
+	if err != nil {
+		t.Fatalf("LoadStepTable failed: %v", err)
+	}
+	estimator.SetTable(table)
+	got := estimator.scaleWithClusterSize(ClusterSize{Nodes: 100, Pods: 10})
+	verifyResources(t, "requests", got.Requests, api.ResourceList{"cpu": resource.MustParse("1")})
+	if dimensions := estimator.dimensions(); len(dimensions) != 1 || dimensions[0] != PodsDimension {
+		t.Errorf("dimensions got %v, want [pods]", dimensions)
+	}
+}
+
+func TestStepEstimatorSnapshot(t *testing.T) {
+	table, err := LoadStepTable(strings.NewReader(`{"steps": [{"size": 0, "requests": {"cpu": "1"}}]}`))
+	if err != nil {
+		t.Fatalf("LoadStepTable failed: %v", err)
+	}
+	estimator := NewStepEstimator(table)
+	snapshot := estimator.snapshot()
+
+	// Replacing the table doesn't change the snapshot.
+	table, err = LoadStepTable(strings.NewReader(`{"dimension": "pods", "steps": [{"size": 0, "requests": {"cpu": "2"}}]}`))
+	if err != nil {
+		t.Fatalf("LoadStepTable failed: %v", err)
+	}
+	estimator.SetTable(table)
+	if dimensions := snapshot.dimensions(); len(dimensions) != 1 || dimensions[0] != NodesDimension {
+		t.Errorf("snapshot dimensions got %v, want [nodes]", dimensions)
+	}
+	verifyResources(t, "requests", snapshot.scaleWithClusterSize(ClusterSize{Nodes: 10}).Requests,
+		api.ResourceList{"cpu": resource.MustParse("1")})
+	verifyResources(t, "requests", estimator.snapshot().scaleWithClusterSize(ClusterSize{Pods: 10}).Requests,
+		api.ResourceList{"cpu": resource.MustParse("2")})
+}
+
+func TestLoadStepTableInvalid(t *testing.T) {
+	for _, content := range []string{
+		`steps: []`,
+		`{"dimension": "volumes", "steps": [{"size": 0, "requests": {"cpu": "1"}}]}`,
+		`{"steps": [{"size": 0, "requests": {"cpu": "1"}}, {"size": 0, "requests": {"cpu": "2"}}]}`,
+		`{"steps": [{"size": 0, "requests": {"cpu": "1"}, "limits": {"cpu": "500m"}}]}`,
+		`{"steps": [{"size": 0, "requests": {"cpu": "abc"}}]}`,
+	} {
+		if _, err := LoadStepTable(strings.NewReader(content)); err == nil {
+			t.Errorf("LoadStepTable didn't fail for %s", content)
+		}
+	}
+}
+
+func TestStepEstimatorReloadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "step-estimator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "steps.yaml")
+	if err := ioutil.WriteFile(path, []byte(stepTable), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	estimator, err := LoadStepEstimator(path)
+	if err != nil {
+		t.Fatalf("LoadStepEstimator failed: %v", err)
+	}
+	want := api.ResourceList{"cpu": resource.MustParse("150m"), "memory": resource.MustParse("200Mi")}
+	verifyResources(t, "requests", estimator.scaleWithClusterSize(ClusterSize{Nodes: 10}).Requests, want)
+
+	// An invalid table is ignored.
+	if err := ioutil.WriteFile(path, []byte("steps: []"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	estimator.reloadFile()
+	verifyResources(t, "requests", estimator.scaleWithClusterSize(ClusterSize{Nodes: 10}).Requests, want)
+
+	if err := ioutil.WriteFile(path, []byte(`{"steps": [{"size": 0, "requests": {"cpu": "1"}}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	estimator.reloadFile()
+	verifyResources(t, "requests", estimator.scaleWithClusterSize(ClusterSize{Nodes: 10}).Requests,
+		api.ResourceList{"cpu": resource.MustParse("1")})
+}
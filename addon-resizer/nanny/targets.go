@@ -0,0 +1,189 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nanny
+
+import (
+	"fmt"
+	"io"
+
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/util/yaml"
+)
+
+const defaultScaleFactor = 1.5
+
+// Target is a container of a deployment resized by the nanny, with the
+// settings of its estimator.
+type Target struct {
+	Namespace  string `json:"namespace"`
+	Deployment string `json:"deployment"`
+	Container  string `json:"container"`
+	// Threshold is a number between 0-100. The container's resources are
+	// rewritten when they deviate from expected by more than threshold.
+	Threshold uint64 `json:"threshold"`
+	// Estimator is one of linear (the default), exponential or step.
+	Estimator string `json:"estimator,omitempty"`
+	// ScaleFactor of the exponential estimator, defaults to 1.5.
+	ScaleFactor float64 `json:"scaleFactor,omitempty"`
+	// Resources of the linear and exponential estimators.
+	Resources []Resource `json:"resources,omitempty"`
+	// StepTable of the step estimator.
+	StepTable *StepTable `json:"stepTable,omitempty"`
+	// ScaleUpCooldown, ScaleDownCooldown and GrowOnlyHours override the
+	// nanny's resize policy for the target.
+	ScaleUpCooldown   *unversioned.Duration `json:"scaleUpCooldown,omitempty"`
+	ScaleDownCooldown *unversioned.Duration `json:"scaleDownCooldown,omitempty"`
+	GrowOnlyHours     string                `json:"growOnlyHours,omitempty"`
+}
+
+// TargetsConfig lists the targets of a nanny.
+type TargetsConfig struct {
+	Targets []Target `json:"targets"`
+}
+
+// LoadTargetsConfig reads a TargetsConfig from a YAML or JSON stream and
+// validates it. Targets missing a namespace get the default namespace.
+func LoadTargetsConfig(reader io.Reader, defaultNamespace string) (*TargetsConfig, error) {
+	config := &TargetsConfig{}
+	if err := yaml.NewYAMLOrJSONDecoder(reader, 4096).Decode(config); err != nil {
+		return nil, fmt.Errorf("Failed to decode targets config: %v", err)
+	}
+	if len(config.Targets) == 0 {
+		return nil, fmt.Errorf("Targets config has no targets.")
+	}
+	seen := make(map[string]bool)
+	for i := range config.Targets {
+		target := &config.Targets[i]
+		if target.Namespace == "" {
+			target.Namespace = defaultNamespace
+		}
+		if err := target.validate(); err != nil {
+			return nil, fmt.Errorf("Invalid target %s: %v", target.Name(), err)
+		}
+		if seen[target.Name()] {
+			return nil, fmt.Errorf("Target %s is configured more than once.", target.Name())
+		}
+		seen[target.Name()] = true
+	}
+	return config, nil
+}
+
+// Name identifies the target as namespace/deployment/container.
+func (t *Target) Name() string {
+	return fmt.Sprintf("%s/%s/%s", t.Namespace, t.Deployment, t.Container)
+}
+
+// validate checks the target, defaulting its estimator settings.
+func (t *Target) validate() error {
+	if t.Deployment == "" {
+		return fmt.Errorf("Must specify a deployment.")
+	}
+	if t.Container == "" {
+		return fmt.Errorf("Must specify a container.")
+	}
+	if t.Threshold > 100 {
+		return fmt.Errorf("Threshold must be between 0 and 100 inclusively, was %d.", t.Threshold)
+	}
+	if (t.ScaleUpCooldown != nil && t.ScaleUpCooldown.Duration < 0) || (t.ScaleDownCooldown != nil && t.ScaleDownCooldown.Duration < 0) {
+		return fmt.Errorf("Cooldowns must not be negative.")
+	}
+	if t.GrowOnlyHours != "" {
+		if _, err := ParseDailyHours(t.GrowOnlyHours); err != nil {
+			return err
+		}
+	}
+	for _, r := range t.Resources {
+		if r.Name == "" {
+			return fmt.Errorf("Must specify the name of each resource.")
+		}
+		if r.Dimension != "" {
+			if _, err := ParseDimension(string(r.Dimension)); err != nil {
+				return err
+			}
+		}
+		if r.LimitRatio != 0 && r.LimitRatio < 1 {
+			return fmt.Errorf("The %s limit ratio must be at least 1, was %f.", r.Name, r.LimitRatio)
+		}
+		if r.LimitRatio != 0 && r.LimitBase != nil {
+			return fmt.Errorf("Only one of the %s limit base and limit ratio can be set.", r.Name)
+		}
+		if r.LimitBase == nil && !r.LimitExtraPerNode.IsZero() {
+			return fmt.Errorf("The %s limit extra per node can only be set with the limit base.", r.Name)
+		}
+	}
+	switch t.Estimator {
+	case "":
+		t.Estimator = "linear"
+	case "linear":
+	case "exponential":
+		if t.ScaleFactor == 0 {
+			t.ScaleFactor = defaultScaleFactor
+		}
+		if t.ScaleFactor <= 1 {
+			return fmt.Errorf("Scale factor must be greater than 1, was %f.", t.ScaleFactor)
+		}
+	case "step":
+		if t.StepTable == nil {
+			return fmt.Errorf("Must specify a step table for the step estimator.")
+		}
+		return t.StepTable.validate()
+	default:
+		return fmt.Errorf("Estimator %s not supported", t.Estimator)
+	}
+	return nil
+}
+
+// BuildEstimator builds the estimator of the target.
+func (t *Target) BuildEstimator() (ResourceEstimator, error) {
+	switch t.Estimator {
+	case "", "linear":
+		return LinearEstimator{
+			Resources: t.Resources,
+		}, nil
+	case "exponential":
+		return ExponentialEstimator{
+			Resources:   t.Resources,
+			ScaleFactor: t.ScaleFactor,
+		}, nil
+	case "step":
+		if t.StepTable == nil {
+			return nil, fmt.Errorf("Must specify a step table for the step estimator.")
+		}
+		return NewStepEstimator(t.StepTable), nil
+	}
+	return nil, fmt.Errorf("Estimator %s not supported", t.Estimator)
+}
+
+// ResizePolicy builds the resize policy of the target, using the defaults for
+// the settings missing in the target.
+func (t *Target) ResizePolicy(defaults ResizePolicy) (ResizePolicy, error) {
+	policy := defaults
+	if t.ScaleUpCooldown != nil {
+		policy.ScaleUpCooldown = t.ScaleUpCooldown.Duration
+	}
+	if t.ScaleDownCooldown != nil {
+		policy.ScaleDownCooldown = t.ScaleDownCooldown.Duration
+	}
+	if t.GrowOnlyHours != "" {
+		hours, err := ParseDailyHours(t.GrowOnlyHours)
+		if err != nil {
+			return ResizePolicy{}, err
+		}
+		policy.GrowOnlyHours = hours
+	}
+	return policy, nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nanny
+
+import (
+	"strings"
+	"testing"
+
+	resource "k8s.io/kubernetes/pkg/api/resource"
+	api "k8s.io/kubernetes/pkg/api/v1"
+)
+
+const targetsConfig = `
+targets:
+- deployment: heapster
+  container: heapster
+  threshold: 5
+  resources:
+  - name: cpu
+    base: 80m
+    extraPerNode: 500m
+  - name: memory
+    base: 140Mi
+    extraPerNode: 4Mi
+    limitRatio: 1.5
+- namespace: monitoring
+  deployment: metrics
+  container: metrics
+  estimator: exponential
+  resources:
+  - name: memory
+    base: 100Mi
+    extraPerNode: 1Mi
+    dimension: pods
+- deployment: dns
+  container: dns
+  estimator: step
+  stepTable:
+    steps:
+    - size: 10
+      requests:
+        cpu: 200m
+    - size: 0
+      requests:
+        cpu: 100m
+`
+
+func TestLoadTargetsConfig(t *testing.T) {
+	config, err := LoadTargetsConfig(strings.NewReader(targetsConfig), "kube-system")
+	if err != nil {
+		t.Fatalf("LoadTargetsConfig failed: %v", err)
+	}
+	if len(config.Targets) != 3 {
+		t.Fatalf("got %d targets, want 3", len(config.Targets))
+	}
+
+	names := []string{"kube-system/heapster/heapster", "monitoring/metrics/metrics", "kube-system/dns/dns"}
+	for i, name := range names {
+		if got := config.Targets[i].Name(); got != name {
+			t.Errorf("target %d got name %s, want %s", i, got, name)
+		}
+	}
+
+	testCases := []struct {
+		target   Target
+		size     ClusterSize
+		limits   api.ResourceList
+		requests api.ResourceList
+	}{
+		{config.Targets[0], ClusterSize{Nodes: 10},
+			api.ResourceList{"cpu": resource.MustParse("5080m"), "memory": resource.MustParse("270Mi")},
+			api.ResourceList{"cpu": resource.MustParse("5080m"), "memory": resource.MustParse("180Mi")}},
+		// The exponential estimator rounds 10 pods up to 16.
+		{config.Targets[1], ClusterSize{Nodes: 1, Pods: 10},
+			api.ResourceList{"memory": resource.MustParse("116Mi")},
+			api.ResourceList{"memory": resource.MustParse("116Mi")}},
+		{config.Targets[2], ClusterSize{Nodes: 12},
+			api.ResourceList{"cpu": resource.MustParse("200m")},
+			api.ResourceList{"cpu": resource.MustParse("200m")}},
+	}
+	for _, tc := range testCases {
+		est, err := tc.target.BuildEstimator()
+		if err != nil {
+			t.Errorf("BuildEstimator failed for %s: %v", tc.target.Name(), err)
+			continue
+		}
+		got := est.scaleWithClusterSize(tc.size)
+		verifyResources(t, "limits", got.Limits, tc.limits)
+		verifyResources(t, "requests", got.Requests, tc.requests)
+	}
+}
+
+func TestLoadInvalidTargetsConfig(t *testing.T) {
+	testCases := []string{
+		`targets: []`,
+		`targets: [{container: heapster}]`,
+		`targets: [{deployment: heapster}]`,
+		`targets: [{deployment: heapster, container: heapster, threshold: 101}]`,
+		`targets: [{deployment: heapster, container: heapster, estimator: magic}]`,
+		`targets: [{deployment: heapster, container: heapster, estimator: exponential, scaleFactor: 0.5}]`,
+		`targets: [{deployment: heapster, container: heapster, estimator: step}]`,
+		`targets: [{deployment: heapster, container: heapster, resources: [{name: cpu, base: 1, dimension: racks}]}]`,
+		`targets: [{deployment: heapster, container: heapster, resources: [{name: cpu, base: 1, limitRatio: 0.5}]}]`,
+		`targets: [{deployment: heapster, container: heapster, resources: [{name: cpu, base: 1, limitExtraPerNode: 100m}]}]`,
+		`targets: [{deployment: heapster, container: heapster, resources: [{name: cpu, base: 1, limitRatio: 2, limitExtraPerNode: 100m}]}]`,
+		`targets: [{deployment: heapster, container: heapster}, {deployment: heapster, container: heapster}]`,
+	}
+	for _, tc := range testCases {
+		if _, err := LoadTargetsConfig(strings.NewReader(tc), "kube-system"); err == nil {
+			t.Errorf("LoadTargetsConfig succeeded for invalid config %s", tc)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"time"
+
+	"k8s.io/contrib/cluster-autoscaler/cloudprovider"
+	"k8s.io/contrib/cluster-autoscaler/expander"
+	"k8s.io/contrib/cluster-autoscaler/simulator"
+	kube_record "k8s.io/kubernetes/pkg/client/record"
+	kube_client "k8s.io/kubernetes/pkg/client/unversioned"
+)
+
+// AutoscalingContext contains user-configurable constants and configuration-related objects
+// passed to scale up/scale down functions.
+type AutoscalingContext struct {
+	// CloudProvider used in CA.
+	CloudProvider cloudprovider.CloudProvider
+	// KubeClient used to talk to the Kubernetes master.
+	KubeClient *kube_client.Client
+	// RequiredPodsLister lists the pods that would run on a new node, like DaemonSet pods.
+	RequiredPodsLister simulator.RequiredPodsLister
+	// Recorder for recording events.
+	Recorder kube_record.EventRecorder
+	// StatusTracker records scale up and scale down times for the status.
+	StatusTracker *StatusTracker
+	// ScaleUpTracker tracks requested nodes and excludes failing node groups from scale up.
+	ScaleUpTracker *ScaleUpTracker
+	// UnhealthyNodeGroups are node groups whose size doesn't match the number of ready nodes in
+	// the current loop. They are neither scaled up nor scaled down.
+	UnhealthyNodeGroups map[string]bool
+	// ScaleDownDisabledNodes are the nodes skipped in scale down in the previous loop, with the
+	// reason. It is used to record an event only when a node starts being skipped.
+	ScaleDownDisabledNodes map[string]string
+	// PredicateChecker to check if a pod can fit into a node.
+	PredicateChecker *simulator.PredicateChecker
+	// ExpanderStrategy is the strategy used to choose which node group to expand when scaling up.
+	ExpanderStrategy expander.Strategy
+	// EstimatorName is the estimator used to estimate the number of needed nodes in scale up.
+	EstimatorName string
+	// BalanceSimilarNodeGroups enables splitting scale ups between node groups with similar nodes,
+	// for example the same machines in different zones.
+	BalanceSimilarNodeGroups bool
+	// MaxTotalCores is the maximum number of cores in all node groups together. 0 means no limit.
+	MaxTotalCores int64
+	// MaxTotalMemory is the maximum number of bytes of memory in all node groups together.
+	// 0 means no limit.
+	MaxTotalMemory int64
+	// ScaleDownUtilizationThreshold sets the utilization level below which a node is considered
+	// for removal.
+	ScaleDownUtilizationThreshold float64
+	// ScaleDownUnneededTime sets the duration CA expects a node to be unneeded/eligible for removal
+	// before scaling down the node.
+	ScaleDownUnneededTime time.Duration
+	// NodeGroupScaleDownOptions overrides the scale down settings for some node groups, keyed by
+	// node group id.
+	NodeGroupScaleDownOptions map[string]NodeGroupScaleDownOptions
+	// MaxEmptyBulkDelete is the maximum number of empty nodes that can be removed at the same time.
+	MaxEmptyBulkDelete int
+	// MaxNonEmptyBulkDelete is the maximum number of non-empty nodes that can be removed in one
+	// scale down iteration.
+	MaxNonEmptyBulkDelete int
+	// MaxDrainTime is the maximum time CA waits for the evicted pods to leave a node and for
+	// their replacements to be scheduled before giving up and bringing the node back to service.
+	MaxDrainTime time.Duration
+}
+
+// NodeGroupScaleDownOptions contains the scale down settings of a single node group. Nil values
+// mean that the AutoscalingContext settings are used.
+type NodeGroupScaleDownOptions struct {
+	// ScaleDownUtilizationThreshold overrides AutoscalingContext.ScaleDownUtilizationThreshold.
+	ScaleDownUtilizationThreshold *float64
+	// ScaleDownUnneededTime overrides AutoscalingContext.ScaleDownUnneededTime.
+	ScaleDownUnneededTime *time.Duration
+}
+
+// GetScaleDownUtilizationThreshold returns the scale down utilization threshold of the node group.
+func (context *AutoscalingContext) GetScaleDownUtilizationThreshold(nodeGroupId string) float64 {
+	if options, found := context.NodeGroupScaleDownOptions[nodeGroupId]; found && options.ScaleDownUtilizationThreshold != nil {
+		return *options.ScaleDownUtilizationThreshold
+	}
+	return context.ScaleDownUtilizationThreshold
+}
+
+// GetScaleDownUnneededTime returns the scale down unneeded time of the node group.
+func (context *AutoscalingContext) GetScaleDownUnneededTime(nodeGroupId string) time.Duration {
+	if options, found := context.NodeGroupScaleDownOptions[nodeGroupId]; found && options.ScaleDownUnneededTime != nil {
+		return *options.ScaleDownUnneededTime
+	}
+	return context.ScaleDownUnneededTime
+}
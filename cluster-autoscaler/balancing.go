@@ -0,0 +1,110 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/contrib/cluster-autoscaler/cloudprovider"
+	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
+)
+
+// Labels that may differ between nodes of similar node groups.
+var ignoredLabelsForSimilarity = map[string]bool{
+	unversioned.LabelHostname:          true,
+	unversioned.LabelZoneFailureDomain: true,
+	unversioned.LabelZoneRegion:        true,
+}
+
+// nodeGroupScaleUp is a planned size increase of a node group.
+type nodeGroupScaleUp struct {
+	nodeGroup   cloudprovider.NodeGroup
+	currentSize int
+	newSize     int
+}
+
+// IsNodeInfoSimilar returns true if the nodes have the same capacity, allocatable resources and
+// labels, apart from the hostname and zone labels.
+func IsNodeInfoSimilar(n1, n2 *schedulercache.NodeInfo) bool {
+	node1, node2 := n1.Node(), n2.Node()
+	if !resourceListsEqual(node1.Status.Capacity, node2.Status.Capacity) ||
+		!resourceListsEqual(node1.Status.Allocatable, node2.Status.Allocatable) {
+		return false
+	}
+	return labelsEqual(node1.Labels, node2.Labels) && labelsEqual(node2.Labels, node1.Labels)
+}
+
+func resourceListsEqual(r1, r2 kube_api.ResourceList) bool {
+	if len(r1) != len(r2) {
+		return false
+	}
+	for name, quantity1 := range r1 {
+		quantity2, found := r2[name]
+		if !found || quantity1.Cmp(quantity2) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// labelsEqual checks if all not ignored labels of l1 have the same value in l2.
+func labelsEqual(l1, l2 map[string]string) bool {
+	for key, value := range l1 {
+		if ignoredLabelsForSimilarity[key] {
+			continue
+		}
+		if other, found := l2[key]; !found || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+// balanceScaleUp splits nodeCount new nodes between the node groups so that their sizes are as
+// even as possible. Node groups don't grow above their max size, so fewer nodes may be added.
+func balanceScaleUp(scaleUps []*nodeGroupScaleUp, nodeCount int) {
+	for i := 0; i < nodeCount; i++ {
+		var smallest *nodeGroupScaleUp
+		for _, scaleUp := range scaleUps {
+			if scaleUp.newSize >= scaleUp.nodeGroup.MaxSize() {
+				continue
+			}
+			if smallest == nil || scaleUp.newSize < smallest.newSize {
+				smallest = scaleUp
+			}
+		}
+		if smallest == nil {
+			return
+		}
+		smallest.newSize++
+	}
+}
+
+// describeScaleUps returns a human readable list of the node group size changes.
+func describeScaleUps(scaleUps []*nodeGroupScaleUp) string {
+	descriptions := make([]string, 0, len(scaleUps))
+	for _, scaleUp := range scaleUps {
+		if scaleUp.newSize > scaleUp.currentSize {
+			descriptions = append(descriptions, fmt.Sprintf("%s %d/%d", scaleUp.nodeGroup.Id(),
+				scaleUp.currentSize, scaleUp.newSize))
+		}
+	}
+	return strings.Join(descriptions, ", ")
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	testprovider "k8s.io/contrib/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/contrib/cluster-autoscaler/estimator"
+	"k8s.io/contrib/cluster-autoscaler/expander"
+	"k8s.io/contrib/cluster-autoscaler/simulator"
+	. "k8s.io/contrib/cluster-autoscaler/utils/test"
+
+	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	kube_record "k8s.io/kubernetes/pkg/client/record"
+	"k8s.io/kubernetes/pkg/runtime"
+	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildLabeledNodeInfo(name string, cpu int64, zone string, labels map[string]string) *schedulercache.NodeInfo {
+	node := BuildTestNode(name, cpu, 1000)
+	node.Labels = map[string]string{
+		unversioned.LabelHostname:          name,
+		unversioned.LabelZoneFailureDomain: zone,
+	}
+	for key, value := range labels {
+		node.Labels[key] = value
+	}
+	nodeInfo := schedulercache.NewNodeInfo()
+	nodeInfo.SetNode(node)
+	return nodeInfo
+}
+
+func TestIsNodeInfoSimilar(t *testing.T) {
+	n1 := buildLabeledNodeInfo("n1", 1000, "zone-a", map[string]string{"pool": "default"})
+	n2 := buildLabeledNodeInfo("n2", 1000, "zone-b", map[string]string{"pool": "default"})
+	n3 := buildLabeledNodeInfo("n3", 2000, "zone-b", map[string]string{"pool": "default"})
+	n4 := buildLabeledNodeInfo("n4", 1000, "zone-b", map[string]string{"pool": "other"})
+	n5 := buildLabeledNodeInfo("n5", 1000, "zone-b", map[string]string{"pool": "default", "gpu": "true"})
+
+	assert.True(t, IsNodeInfoSimilar(n1, n2))
+	assert.False(t, IsNodeInfoSimilar(n1, n3))
+	assert.False(t, IsNodeInfoSimilar(n1, n4))
+	assert.False(t, IsNodeInfoSimilar(n1, n5))
+	assert.False(t, IsNodeInfoSimilar(n5, n1))
+}
+
+func TestBalanceScaleUp(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	ng1 := provider.AddNodeGroup("ng1", 1, 10, 3)
+	ng2 := provider.AddNodeGroup("ng2", 1, 10, 1)
+	ng3 := provider.AddNodeGroup("ng3", 1, 2, 1)
+
+	scaleUps := []*nodeGroupScaleUp{
+		{nodeGroup: ng1, currentSize: 3, newSize: 3},
+		{nodeGroup: ng2, currentSize: 1, newSize: 1},
+		{nodeGroup: ng3, currentSize: 1, newSize: 1},
+	}
+	balanceScaleUp(scaleUps, 5)
+	assert.Equal(t, 4, scaleUps[0].newSize)
+	assert.Equal(t, 4, scaleUps[1].newSize)
+	assert.Equal(t, 2, scaleUps[2].newSize)
+	assert.Equal(t, "ng1 3/4, ng2 1/4, ng3 1/2", describeScaleUps(scaleUps))
+
+	// All node groups reach their max size.
+	ng4 := provider.AddNodeGroup("ng4", 1, 2, 1)
+	scaleUps = []*nodeGroupScaleUp{{nodeGroup: ng4, currentSize: 1, newSize: 1}}
+	balanceScaleUp(scaleUps, 3)
+	assert.Equal(t, 2, scaleUps[0].newSize)
+}
+
+func TestScaleUpBalanceSimilarNodeGroups(t *testing.T) {
+	n1 := buildLabeledNodeInfo("n1", 1000, "zone-a", nil).Node()
+	n2 := buildLabeledNodeInfo("n2", 1000, "zone-b", nil).Node()
+	n3 := buildLabeledNodeInfo("n3", 1000, "zone-c", nil).Node()
+
+	scaledUp := make(map[string]int)
+	provider := testprovider.NewTestCloudProvider(func(id string, size int) error {
+		scaledUp[id] = size
+		return nil
+	}, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNode("ng1", n1)
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	provider.AddNode("ng2", n2)
+	provider.AddNodeGroup("ng3", 1, 2, 1)
+	provider.AddNode("ng3", n3)
+
+	client, server := NewTestKubeClient(func(req *http.Request) runtime.Object {
+		if req.URL.Path == "/api/v1/pods" {
+			return BuildTestPodList()
+		}
+		return nil
+	})
+	defer server.Close()
+
+	context := &AutoscalingContext{
+		CloudProvider:            provider,
+		StatusTracker:            NewStatusTracker(),
+		ScaleUpTracker:           NewScaleUpTracker(time.Minute),
+		KubeClient:               client,
+		RequiredPodsLister:       simulator.NewClientRequiredPodsLister(client),
+		Recorder:                 kube_record.NewFakeRecorder(10),
+		PredicateChecker:         simulator.NewTestPredicateChecker(),
+		ExpanderStrategy:         expander.NewRandomStrategy(),
+		EstimatorName:            estimator.BinpackingEstimatorName,
+		BalanceSimilarNodeGroups: true,
+	}
+	pods := []*kube_api.Pod{}
+	for _, name := range []string{"p1", "p2", "p3", "p4", "p5"} {
+		pods = append(pods, BuildTestPod(name, 800, 0))
+	}
+	result, err := ScaleUp(context, pods, []*kube_api.Node{n1, n2, n3})
+
+	assert.NoError(t, err)
+	assert.True(t, result)
+	assert.Equal(t, map[string]int{"ng1": 3, "ng2": 3, "ng3": 2}, scaledUp)
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"errors"
+
+	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
+)
+
+// ErrNotImplemented is returned if a method is not implemented by a cloud provider.
+var ErrNotImplemented = errors.New("not implemented")
+
+// CloudProvider contains configuration info and functions for interacting with
+// cloud provider (GCE, AWS, etc).
+type CloudProvider interface {
+	// NodeGroups returns all node groups configured for this cloud provider.
+	NodeGroups() []NodeGroup
+
+	// NodeGroupForNode returns the node group for the given node. It returns an
+	// error if the node doesn't belong to any known node group or the lookup failed.
+	NodeGroupForNode(node *kube_api.Node) (NodeGroup, error)
+}
+
+// NodeGroup contains configuration info and functions to control a set
+// of nodes that have the same capacity and set of labels.
+type NodeGroup interface {
+	// MaxSize returns maximum size of the node group.
+	MaxSize() int
+
+	// MinSize returns minimum size of the node group.
+	MinSize() int
+
+	// TargetSize returns the current target size of the node group. It is possible that the
+	// number of nodes in Kubernetes is different at the moment but should be equal
+	// to TargetSize() once everything stabilizes (new nodes finish startup and registration or
+	// removed nodes are deleted completely).
+	TargetSize() (int, error)
+
+	// SetTargetSize sets the target size of the node group. The caller is responsible
+	// for keeping the size within MinSize() and MaxSize().
+	SetTargetSize(size int) error
+
+	// DeleteNodes deletes the given nodes from the node group. All nodes must belong
+	// to this node group. The target size is decreased accordingly.
+	DeleteNodes(nodes []*kube_api.Node) error
+
+	// Nodes returns the ids of all instances of the node group, including the ones that haven't
+	// registered in Kubernetes. The ids have the format of Node.Spec.ProviderID.
+	Nodes() ([]string, error)
+
+	// Id returns an unique identifier of the node group.
+	Id() string
+
+	// Debug returns a string containing all information regarding this node group.
+	Debug() string
+
+	// TemplateNodeInfo returns a schedulercache.NodeInfo structure of an empty
+	// (as if just started) node. It may be used to predict how a new node would
+	// look like. ErrNotImplemented is returned if the provider can't build it
+	// without a live node.
+	TemplateNodeInfo() (*schedulercache.NodeInfo, error)
+}
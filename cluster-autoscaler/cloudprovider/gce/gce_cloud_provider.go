@@ -0,0 +1,154 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"fmt"
+
+	"k8s.io/contrib/cluster-autoscaler/cloudprovider"
+	"k8s.io/contrib/cluster-autoscaler/config"
+	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
+)
+
+// GceCloudProvider implements CloudProvider interface.
+type GceCloudProvider struct {
+	gceManager *GceManager
+	migs       []*Mig
+}
+
+// BuildGceCloudProvider builds CloudProvider implementation for GCE.
+func BuildGceCloudProvider(gceManager *GceManager, migConfigs []*config.MigConfig) *GceCloudProvider {
+	gce := &GceCloudProvider{
+		gceManager: gceManager,
+	}
+	gce.buildMigs(migConfigs)
+	return gce
+}
+
+// SetMigConfigs replaces the MIGs controlled by the cloud provider.
+func (gce *GceCloudProvider) SetMigConfigs(migConfigs []*config.MigConfig) {
+	gce.gceManager.SetMigs(migConfigs)
+	gce.buildMigs(migConfigs)
+}
+
+func (gce *GceCloudProvider) buildMigs(migConfigs []*config.MigConfig) {
+	migs := make([]*Mig, 0, len(migConfigs))
+	for _, migConfig := range migConfigs {
+		migs = append(migs, &Mig{
+			migConfig:  migConfig,
+			gceManager: gce.gceManager,
+		})
+	}
+	gce.migs = migs
+}
+
+// NodeGroups returns all node groups configured for this cloud provider.
+func (gce *GceCloudProvider) NodeGroups() []cloudprovider.NodeGroup {
+	result := make([]cloudprovider.NodeGroup, 0, len(gce.migs))
+	for _, mig := range gce.migs {
+		result = append(result, mig)
+	}
+	return result
+}
+
+// NodeGroupForNode returns the node group for the given node.
+func (gce *GceCloudProvider) NodeGroupForNode(node *kube_api.Node) (cloudprovider.NodeGroup, error) {
+	instance, err := config.InstanceConfigFromProviderId(node.Spec.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+	migConfig, err := gce.gceManager.GetMigForInstance(instance)
+	if err != nil {
+		return nil, err
+	}
+	for _, mig := range gce.migs {
+		if mig.migConfig == migConfig {
+			return mig, nil
+		}
+	}
+	return nil, fmt.Errorf("Mig %s is not managed by the cloud provider", migConfig.Url())
+}
+
+// Mig implements NodeGroup interface.
+type Mig struct {
+	migConfig  *config.MigConfig
+	gceManager *GceManager
+}
+
+// MaxSize returns maximum size of the node group.
+func (mig *Mig) MaxSize() int {
+	return mig.migConfig.MaxSize
+}
+
+// MinSize returns minimum size of the node group.
+func (mig *Mig) MinSize() int {
+	return mig.migConfig.MinSize
+}
+
+// TargetSize returns the current target size of the node group.
+func (mig *Mig) TargetSize() (int, error) {
+	size, err := mig.gceManager.GetMigSize(mig.migConfig)
+	return int(size), err
+}
+
+// SetTargetSize sets the target size of the node group.
+func (mig *Mig) SetTargetSize(size int) error {
+	return mig.gceManager.SetMigSize(mig.migConfig, int64(size))
+}
+
+// DeleteNodes deletes the given nodes from the node group.
+func (mig *Mig) DeleteNodes(nodes []*kube_api.Node) error {
+	instances := make([]*config.InstanceConfig, 0, len(nodes))
+	for _, node := range nodes {
+		instance, err := config.InstanceConfigFromProviderId(node.Spec.ProviderID)
+		if err != nil {
+			return fmt.Errorf("Failed to get instance config for %s: %v", node.Name, err)
+		}
+		instances = append(instances, instance)
+	}
+	return mig.gceManager.DeleteInstances(instances)
+}
+
+// Nodes returns the provider ids of all instances of the mig.
+func (mig *Mig) Nodes() ([]string, error) {
+	return mig.gceManager.GetMigNodes(mig.migConfig)
+}
+
+// Id returns mig url.
+func (mig *Mig) Id() string {
+	return mig.migConfig.Url()
+}
+
+// Debug returns a debug string for the Mig.
+func (mig *Mig) Debug() string {
+	return fmt.Sprintf("%s (%d:%d)", mig.Id(), mig.MinSize(), mig.MaxSize())
+}
+
+// TemplateNodeInfo returns a node template for this node group, built from the instance template
+// of the mig. It allows scaling up migs that currently have no nodes.
+func (mig *Mig) TemplateNodeInfo() (*schedulercache.NodeInfo, error) {
+	node, err := mig.gceManager.GetMigTemplateNode(mig.migConfig)
+	if err != nil {
+		return nil, err
+	}
+	nodeInfo := schedulercache.NewNodeInfo()
+	if err := nodeInfo.SetNode(node); err != nil {
+		return nil, err
+	}
+	return nodeInfo, nil
+}
@@ -19,6 +19,7 @@ package gce
 import (
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 	"time"
 
@@ -31,6 +32,7 @@ import (
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	gce "google.golang.org/api/compute/v1"
+	kube_api "k8s.io/kubernetes/pkg/api"
 	provider_gce "k8s.io/kubernetes/pkg/cloudprovider/providers/gce"
 	"k8s.io/kubernetes/pkg/util/wait"
 )
@@ -46,6 +48,16 @@ type GceManager struct {
 	service    *gce.Service
 	migCache   map[config.InstanceConfig]*config.MigConfig
 	cacheMutex sync.Mutex
+
+	// Template nodes keyed by mig url, rebuilt only when the instance template of the mig changes.
+	templateCache      map[string]templateNode
+	templateCacheMutex sync.Mutex
+}
+
+// templateNode is a template node built from the given instance template.
+type templateNode struct {
+	instanceTemplate string
+	node             *kube_api.Node
 }
 
 // CreateGceManager constructs gceManager object.
@@ -75,9 +87,10 @@ func CreateGceManager(migs []*config.MigConfig, configReader io.Reader) (*GceMan
 	}
 
 	manager := &GceManager{
-		migs:     migs,
-		service:  gceService,
-		migCache: map[config.InstanceConfig]*config.MigConfig{},
+		migs:          migs,
+		service:       gceService,
+		migCache:      map[config.InstanceConfig]*config.MigConfig{},
+		templateCache: map[string]templateNode{},
 	}
 
 	go wait.Forever(func() { manager.regenerateCacheIgnoreError() }, time.Hour)
@@ -85,6 +98,18 @@ func CreateGceManager(migs []*config.MigConfig, configReader io.Reader) (*GceMan
 	return manager, nil
 }
 
+// SetMigs replaces the MIGs handled by the manager.
+func (m *GceManager) SetMigs(migs []*config.MigConfig) {
+	m.cacheMutex.Lock()
+	defer m.cacheMutex.Unlock()
+	m.migs = migs
+	m.migCache = map[config.InstanceConfig]*config.MigConfig{}
+
+	m.templateCacheMutex.Lock()
+	defer m.templateCacheMutex.Unlock()
+	m.templateCache = map[string]templateNode{}
+}
+
 // GetMigSize gets MIG size.
 func (m *GceManager) GetMigSize(migConf *config.MigConfig) (int64, error) {
 	mig, err := m.service.InstanceGroupManagers.Get(migConf.Project, migConf.Zone, migConf.Name).Do()
@@ -106,6 +131,71 @@ func (m *GceManager) SetMigSize(migConf *config.MigConfig, size int64) error {
 	return nil
 }
 
+// GetMigNodes returns the provider ids (gce://<project-id>/<zone>/<name>) of all instances of the MIG.
+func (m *GceManager) GetMigNodes(migConf *config.MigConfig) ([]string, error) {
+	instances, err := m.service.InstanceGroupManagers.ListManagedInstances(migConf.Project, migConf.Zone, migConf.Name).Do()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(instances.ManagedInstances))
+	for _, instance := range instances.ManagedInstances {
+		project, zone, name, err := gceurl.ParseInstanceUrl(instance.Instance)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, fmt.Sprintf("gce://%s/%s/%s", project, zone, name))
+	}
+	return result, nil
+}
+
+// GetMigTemplateNode builds a node resembling the nodes that the MIG creates, based on its
+// instance template and machine type. The node is cached and only rebuilt when the MIG starts
+// using a different instance template.
+func (m *GceManager) GetMigTemplateNode(migConf *config.MigConfig) (*kube_api.Node, error) {
+	mig, err := m.service.InstanceGroupManagers.Get(migConf.Project, migConf.Zone, migConf.Name).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	m.templateCacheMutex.Lock()
+	cached, found := m.templateCache[migConf.Url()]
+	m.templateCacheMutex.Unlock()
+	if found && cached.instanceTemplate == mig.InstanceTemplate {
+		return copyNode(cached.node)
+	}
+
+	templateName := mig.InstanceTemplate[strings.LastIndex(mig.InstanceTemplate, "/")+1:]
+	template, err := m.service.InstanceTemplates.Get(migConf.Project, templateName).Do()
+	if err != nil {
+		return nil, err
+	}
+	if template.Properties == nil {
+		return nil, fmt.Errorf("instance template %s has no properties", templateName)
+	}
+	machineType, err := m.service.MachineTypes.Get(migConf.Project, migConf.Zone, template.Properties.MachineType).Do()
+	if err != nil {
+		return nil, err
+	}
+	node, err := buildNodeFromTemplate(migConf, template, machineType)
+	if err != nil {
+		return nil, err
+	}
+
+	m.templateCacheMutex.Lock()
+	m.templateCache[migConf.Url()] = templateNode{instanceTemplate: mig.InstanceTemplate, node: node}
+	m.templateCacheMutex.Unlock()
+	return copyNode(node)
+}
+
+// copyNode returns a deep copy of the node, so that the cached template nodes are never modified.
+func copyNode(node *kube_api.Node) (*kube_api.Node, error) {
+	copied, err := kube_api.Scheme.Copy(node)
+	if err != nil {
+		return nil, err
+	}
+	return copied.(*kube_api.Node), nil
+}
+
 func (m *GceManager) waitForOp(operation *gce.Operation, project string, zone string) error {
 	for start := time.Now(); time.Since(start) < operationWaitTimeout; time.Sleep(operationPollInterval) {
 		glog.V(4).Infof("Waiting for operation %s %s %s", project, zone, operation.Name)
@@ -0,0 +1,171 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"k8s.io/contrib/cluster-autoscaler/config"
+
+	gce "google.golang.org/api/compute/v1"
+	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/resource"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+)
+
+const (
+	// kubeEnvKey is the instance metadata entry with the kubelet configuration.
+	kubeEnvKey = "kube-env"
+	// Maximum number of pods per node, as set by default in kubelet.
+	maxPodsPerNode = 110
+)
+
+// buildNodeFromTemplate builds a node that resembles the nodes that will be created from the given
+// instance template, with the given machine type capacity.
+func buildNodeFromTemplate(migConfig *config.MigConfig, template *gce.InstanceTemplate,
+	machineType *gce.MachineType) (*kube_api.Node, error) {
+
+	if template.Properties == nil {
+		return nil, fmt.Errorf("instance template %s has no properties", template.Name)
+	}
+	nodeName := fmt.Sprintf("%s-template-%d", template.Name, rand.Int63())
+	node := &kube_api.Node{
+		ObjectMeta: kube_api.ObjectMeta{
+			Name:        nodeName,
+			SelfLink:    fmt.Sprintf("/api/v1/nodes/%s", nodeName),
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+		},
+		Status: kube_api.NodeStatus{
+			Capacity: kube_api.ResourceList{
+				kube_api.ResourceCPU:    *resource.NewQuantity(machineType.GuestCpus, resource.DecimalSI),
+				kube_api.ResourceMemory: *resource.NewQuantity(machineType.MemoryMb*1024*1024, resource.DecimalSI),
+				kube_api.ResourcePods:   *resource.NewQuantity(maxPodsPerNode, resource.DecimalSI),
+			},
+			Conditions: []kube_api.NodeCondition{
+				{
+					Type:   kube_api.NodeReady,
+					Status: kube_api.ConditionTrue,
+				},
+			},
+		},
+	}
+	node.Status.Allocatable = node.Status.Capacity
+
+	node.Labels[unversioned.LabelHostname] = nodeName
+	node.Labels[unversioned.LabelInstanceType] = machineType.Name
+	node.Labels[unversioned.LabelZoneFailureDomain] = migConfig.Zone
+	if index := strings.LastIndex(migConfig.Zone, "-"); index > 0 {
+		node.Labels[unversioned.LabelZoneRegion] = migConfig.Zone[:index]
+	}
+	node.Labels[unversioned.LabelOS] = "linux"
+
+	kubeEnv := getKubeEnv(template.Properties.Metadata)
+	labels, err := extractLabelsFromKubeEnv(kubeEnv)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range labels {
+		node.Labels[key] = value
+	}
+	taints, err := extractTaintsFromKubeEnv(kubeEnv)
+	if err != nil {
+		return nil, err
+	}
+	if len(taints) > 0 {
+		taintsJson, err := json.Marshal(taints)
+		if err != nil {
+			return nil, err
+		}
+		node.Annotations[kube_api.TaintsAnnotationKey] = string(taintsJson)
+	}
+	return node, nil
+}
+
+func getKubeEnv(metadata *gce.Metadata) string {
+	if metadata == nil {
+		return ""
+	}
+	for _, item := range metadata.Items {
+		if item.Key == kubeEnvKey && item.Value != nil {
+			return *item.Value
+		}
+	}
+	return ""
+}
+
+// getKubeEnvValue returns the value of the given key from kube-env, which consists of
+// lines in the form KEY: 'value'.
+func getKubeEnvValue(kubeEnv string, key string) string {
+	for _, line := range strings.Split(kubeEnv, "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) != key {
+			continue
+		}
+		return strings.Trim(strings.TrimSpace(parts[1]), "'\"")
+	}
+	return ""
+}
+
+// extractLabelsFromKubeEnv parses NODE_LABELS from kube-env, in the form key1=value1,key2=value2.
+func extractLabelsFromKubeEnv(kubeEnv string) (map[string]string, error) {
+	result := make(map[string]string)
+	labels := getKubeEnvValue(kubeEnv, "NODE_LABELS")
+	if labels == "" {
+		return result, nil
+	}
+	for _, label := range strings.Split(labels, ",") {
+		parts := strings.SplitN(label, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("malformed node label %q", label)
+		}
+		result[parts[0]] = parts[1]
+	}
+	return result, nil
+}
+
+// extractTaintsFromKubeEnv parses NODE_TAINTS from kube-env, in the form key1=value1:Effect1,key2=value2:Effect2.
+func extractTaintsFromKubeEnv(kubeEnv string) ([]kube_api.Taint, error) {
+	result := make([]kube_api.Taint, 0)
+	taints := getKubeEnvValue(kubeEnv, "NODE_TAINTS")
+	if taints == "" {
+		return result, nil
+	}
+	for _, taint := range strings.Split(taints, ",") {
+		keyValue, effect := taint, ""
+		if index := strings.LastIndex(taint, ":"); index >= 0 {
+			keyValue, effect = taint[:index], taint[index+1:]
+		}
+		parts := strings.SplitN(keyValue, "=", 2)
+		if parts[0] == "" || effect == "" {
+			return nil, fmt.Errorf("malformed node taint %q", taint)
+		}
+		value := ""
+		if len(parts) == 2 {
+			value = parts[1]
+		}
+		result = append(result, kube_api.Taint{
+			Key:    parts[0],
+			Value:  value,
+			Effect: kube_api.TaintEffect(effect),
+		})
+	}
+	return result, nil
+}
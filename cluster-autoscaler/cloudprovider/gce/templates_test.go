@@ -0,0 +1,134 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/contrib/cluster-autoscaler/config"
+
+	gce "google.golang.org/api/compute/v1"
+	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildNodeFromTemplate(t *testing.T) {
+	kubeEnv := "ENABLE_NODE_PROBLEM_DETECTOR: 'daemonset'\n" +
+		"NODE_LABELS: 'cloud.google.com/gke-nodepool=pool-3,gpu=true'\n" +
+		"NODE_TAINTS: 'dedicated=ml:NoSchedule,spot:PreferNoSchedule'\n"
+	template := &gce.InstanceTemplate{
+		Name: "pool-3-template",
+		Properties: &gce.InstanceProperties{
+			MachineType: "n1-highmem-8",
+			Metadata: &gce.Metadata{
+				Items: []*gce.MetadataItems{{Key: "kube-env", Value: &kubeEnv}},
+			},
+		},
+	}
+	machineType := &gce.MachineType{Name: "n1-highmem-8", GuestCpus: 8, MemoryMb: 53248}
+	migConfig := &config.MigConfig{Project: "p", Zone: "us-central1-b", Name: "pool-3"}
+
+	node, err := buildNodeFromTemplate(migConfig, template, machineType)
+	assert.NoError(t, err)
+
+	cpu := node.Status.Capacity[kube_api.ResourceCPU]
+	mem := node.Status.Capacity[kube_api.ResourceMemory]
+	assert.Equal(t, int64(8000), cpu.MilliValue())
+	assert.Equal(t, int64(53248*1024*1024), mem.Value())
+	assert.Equal(t, node.Status.Capacity, node.Status.Allocatable)
+
+	assert.Equal(t, "pool-3", node.Labels["cloud.google.com/gke-nodepool"])
+	assert.Equal(t, "true", node.Labels["gpu"])
+	assert.Equal(t, "n1-highmem-8", node.Labels[unversioned.LabelInstanceType])
+	assert.Equal(t, "us-central1-b", node.Labels[unversioned.LabelZoneFailureDomain])
+	assert.Equal(t, "us-central1", node.Labels[unversioned.LabelZoneRegion])
+
+	taints, err := kube_api.GetTaintsFromNodeAnnotations(node.Annotations)
+	assert.NoError(t, err)
+	assert.Equal(t, []kube_api.Taint{
+		{Key: "dedicated", Value: "ml", Effect: kube_api.TaintEffectNoSchedule},
+		{Key: "spot", Effect: kube_api.TaintEffectPreferNoSchedule},
+	}, taints)
+}
+
+func TestExtractLabelsAndTaintsFromKubeEnv(t *testing.T) {
+	labels, err := extractLabelsFromKubeEnv("OTHER: 'x'\n")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{}, labels)
+
+	_, err = extractLabelsFromKubeEnv("NODE_LABELS: 'novalue'\n")
+	assert.Error(t, err)
+
+	_, err = extractTaintsFromKubeEnv("NODE_TAINTS: 'key=value'\n")
+	assert.Error(t, err)
+}
+
+func TestGetMigTemplateNodeCache(t *testing.T) {
+	instanceTemplate := "https://www.googleapis.com/compute/v1/projects/p/global/instanceTemplates/pool-3-template"
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var response interface{}
+		switch {
+		case strings.Contains(req.URL.Path, "/instanceGroupManagers/"):
+			requests["instanceGroupManagers"]++
+			response = &gce.InstanceGroupManager{InstanceTemplate: instanceTemplate}
+		case strings.Contains(req.URL.Path, "/instanceTemplates/"):
+			requests["instanceTemplates"]++
+			response = &gce.InstanceTemplate{
+				Name:       req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:],
+				Properties: &gce.InstanceProperties{MachineType: "n1-standard-1"},
+			}
+		case strings.Contains(req.URL.Path, "/machineTypes/"):
+			requests["machineTypes"]++
+			response = &gce.MachineType{Name: "n1-standard-1", GuestCpus: 1, MemoryMb: 3840}
+		default:
+			http.NotFound(w, req)
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	service, err := gce.New(http.DefaultClient)
+	assert.NoError(t, err)
+	service.BasePath = server.URL + "/"
+	manager := &GceManager{service: service, templateCache: map[string]templateNode{}}
+	migConfig := &config.MigConfig{Project: "p", Zone: "us-central1-b", Name: "pool-3"}
+
+	node, err := manager.GetMigTemplateNode(migConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, "n1-standard-1", node.Labels[unversioned.LabelInstanceType])
+	// Modifying the returned node doesn't affect the cache.
+	node.Labels["modified"] = "true"
+
+	node, err = manager.GetMigTemplateNode(migConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, "", node.Labels["modified"])
+	assert.Equal(t, map[string]int{"instanceGroupManagers": 2, "instanceTemplates": 1, "machineTypes": 1}, requests)
+
+	// A new instance template is fetched.
+	instanceTemplate = "https://www.googleapis.com/compute/v1/projects/p/global/instanceTemplates/pool-3-template-v2"
+	_, err = manager.GetMigTemplateNode(migConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"instanceGroupManagers": 3, "instanceTemplates": 2, "machineTypes": 2}, requests)
+}
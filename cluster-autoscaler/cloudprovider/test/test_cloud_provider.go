@@ -0,0 +1,237 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"k8s.io/contrib/cluster-autoscaler/cloudprovider"
+	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
+)
+
+// OnScaleUpFunc is a function called on node group increase in TestCloudProvider.
+// First parameter is the NodeGroup id, second is the new target size.
+type OnScaleUpFunc func(string, int) error
+
+// OnScaleDownFunc is a function called on cluster scale down. First parameter
+// is the NodeGroup id, second is the name of the deleted node.
+type OnScaleDownFunc func(string, string) error
+
+// TestCloudProvider is a dummy, in-memory cloud provider to be used in tests.
+type TestCloudProvider struct {
+	sync.Mutex
+	nodes       map[string]string
+	groups      map[string]*TestNodeGroup
+	groupOrder  []string
+	onScaleUp   OnScaleUpFunc
+	onScaleDown OnScaleDownFunc
+}
+
+// NewTestCloudProvider builds new TestCloudProvider. Both callbacks may be nil.
+func NewTestCloudProvider(onScaleUp OnScaleUpFunc, onScaleDown OnScaleDownFunc) *TestCloudProvider {
+	return &TestCloudProvider{
+		nodes:       make(map[string]string),
+		groups:      make(map[string]*TestNodeGroup),
+		groupOrder:  make([]string, 0),
+		onScaleUp:   onScaleUp,
+		onScaleDown: onScaleDown,
+	}
+}
+
+// NodeGroups returns all node groups configured for this cloud provider, in the order
+// in which they were added.
+func (tcp *TestCloudProvider) NodeGroups() []cloudprovider.NodeGroup {
+	tcp.Lock()
+	defer tcp.Unlock()
+
+	result := make([]cloudprovider.NodeGroup, 0, len(tcp.groupOrder))
+	for _, id := range tcp.groupOrder {
+		result = append(result, tcp.groups[id])
+	}
+	return result
+}
+
+// NodeGroupForNode returns the node group for the given node.
+func (tcp *TestCloudProvider) NodeGroupForNode(node *kube_api.Node) (cloudprovider.NodeGroup, error) {
+	tcp.Lock()
+	defer tcp.Unlock()
+
+	groupName, found := tcp.nodes[node.Name]
+	if !found {
+		return nil, fmt.Errorf("node %s doesn't belong to any node group", node.Name)
+	}
+	group, found := tcp.groups[groupName]
+	if !found {
+		return nil, fmt.Errorf("unknown node group %s", groupName)
+	}
+	return group, nil
+}
+
+// AddNodeGroup adds node group to test cloud provider.
+func (tcp *TestCloudProvider) AddNodeGroup(id string, min int, max int, size int) *TestNodeGroup {
+	tcp.Lock()
+	defer tcp.Unlock()
+
+	group := &TestNodeGroup{
+		cloudProvider: tcp,
+		id:            id,
+		minSize:       min,
+		maxSize:       max,
+		targetSize:    size,
+	}
+	if _, found := tcp.groups[id]; !found {
+		tcp.groupOrder = append(tcp.groupOrder, id)
+	}
+	tcp.groups[id] = group
+	return group
+}
+
+// AddNode adds the given node to the group.
+func (tcp *TestCloudProvider) AddNode(nodeGroupId string, node *kube_api.Node) {
+	tcp.Lock()
+	defer tcp.Unlock()
+	tcp.nodes[node.Name] = nodeGroupId
+}
+
+// GetNodeGroup returns the node group with the given id or nil if it doesn't exist.
+func (tcp *TestCloudProvider) GetNodeGroup(id string) *TestNodeGroup {
+	tcp.Lock()
+	defer tcp.Unlock()
+	return tcp.groups[id]
+}
+
+// TestNodeGroup is a node group used by TestCloudProvider.
+type TestNodeGroup struct {
+	sync.Mutex
+	cloudProvider *TestCloudProvider
+	id            string
+	maxSize       int
+	minSize       int
+	targetSize    int
+	template      *schedulercache.NodeInfo
+}
+
+// MaxSize returns maximum size of the node group.
+func (tng *TestNodeGroup) MaxSize() int {
+	tng.Lock()
+	defer tng.Unlock()
+	return tng.maxSize
+}
+
+// MinSize returns minimum size of the node group.
+func (tng *TestNodeGroup) MinSize() int {
+	tng.Lock()
+	defer tng.Unlock()
+	return tng.minSize
+}
+
+// TargetSize returns the current target size of the node group.
+func (tng *TestNodeGroup) TargetSize() (int, error) {
+	tng.Lock()
+	defer tng.Unlock()
+	return tng.targetSize, nil
+}
+
+// SetTargetSize sets the target size of the node group and calls the OnScaleUpFunc
+// callback if the size grows.
+func (tng *TestNodeGroup) SetTargetSize(size int) error {
+	tng.Lock()
+	grows := size > tng.targetSize
+	tng.targetSize = size
+	tng.Unlock()
+
+	if grows && tng.cloudProvider.onScaleUp != nil {
+		return tng.cloudProvider.onScaleUp(tng.id, size)
+	}
+	return nil
+}
+
+// DeleteNodes deletes the given nodes from the node group and calls the OnScaleDownFunc
+// callback for each of them.
+func (tng *TestNodeGroup) DeleteNodes(nodes []*kube_api.Node) error {
+	for _, node := range nodes {
+		group, err := tng.cloudProvider.NodeGroupForNode(node)
+		if err != nil {
+			return err
+		}
+		if group.Id() != tng.id {
+			return fmt.Errorf("node %s doesn't belong to node group %s", node.Name, tng.id)
+		}
+	}
+	for _, node := range nodes {
+		tng.Lock()
+		tng.targetSize--
+		tng.Unlock()
+
+		tng.cloudProvider.Lock()
+		delete(tng.cloudProvider.nodes, node.Name)
+		tng.cloudProvider.Unlock()
+
+		if tng.cloudProvider.onScaleDown != nil {
+			if err := tng.cloudProvider.onScaleDown(tng.id, node.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Nodes returns the names of all nodes added to the group, which test nodes use as provider ids.
+func (tng *TestNodeGroup) Nodes() ([]string, error) {
+	tng.cloudProvider.Lock()
+	defer tng.cloudProvider.Unlock()
+	result := make([]string, 0)
+	for node, group := range tng.cloudProvider.nodes {
+		if group == tng.id {
+			result = append(result, node)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// Id returns an unique identifier of the node group.
+func (tng *TestNodeGroup) Id() string {
+	return tng.id
+}
+
+// Debug returns a string containing all information regarding this node group.
+func (tng *TestNodeGroup) Debug() string {
+	tng.Lock()
+	defer tng.Unlock()
+	return fmt.Sprintf("%s target:%d min:%d max:%d", tng.id, tng.targetSize, tng.minSize, tng.maxSize)
+}
+
+// SetTemplateNodeInfo sets the node template returned by TemplateNodeInfo.
+func (tng *TestNodeGroup) SetTemplateNodeInfo(nodeInfo *schedulercache.NodeInfo) {
+	tng.Lock()
+	defer tng.Unlock()
+	tng.template = nodeInfo
+}
+
+// TemplateNodeInfo returns the node template set with SetTemplateNodeInfo.
+func (tng *TestNodeGroup) TemplateNodeInfo() (*schedulercache.NodeInfo, error) {
+	tng.Lock()
+	defer tng.Unlock()
+	if tng.template == nil {
+		return nil, cloudprovider.ErrNotImplemented
+	}
+	return tng.template, nil
+}
@@ -18,15 +18,23 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"time"
 
+	"k8s.io/contrib/cluster-autoscaler/cloudprovider/gce"
 	"k8s.io/contrib/cluster-autoscaler/config"
+	"k8s.io/contrib/cluster-autoscaler/estimator"
+	"k8s.io/contrib/cluster-autoscaler/expander"
 	"k8s.io/contrib/cluster-autoscaler/simulator"
-	"k8s.io/contrib/cluster-autoscaler/utils/gce"
 	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/resource"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/client/leaderelection"
 	kube_record "k8s.io/kubernetes/pkg/client/record"
 	kube_client "k8s.io/kubernetes/pkg/client/unversioned"
 
@@ -34,14 +42,22 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// How often the config file is checked for changes.
+const configFileCheckInterval = 10 * time.Second
+
 var (
 	migConfigFlag           config.MigConfigFlag
-	address                 = flag.String("address", ":8085", "The address to expose prometheus metrics.")
+	address                 = flag.String("address", ":8085", "The address to expose prometheus metrics (/metrics) and the autoscaler status (/status).")
 	kubernetes              = flag.String("kubernetes", "", "Kuberentes master location. Leave blank for default")
 	cloudConfig             = flag.String("cloud-config", "", "The path to the cloud provider configuration file.  Empty string for no configuration file.")
 	verifyUnschedulablePods = flag.Bool("verify-unschedulable-pods", true,
 		"If enabled CA will ensure that each pod marked by Scheduler as unschedulable actually can't be scheduled on any node."+
 			"This prevents from adding unnecessary nodes in situation when CA and Scheduler have different configuration.")
+	balanceSimilarNodeGroups = flag.Bool("balance-similar-node-groups", false,
+		"If enabled CA splits scale ups between node groups with the same capacity and labels apart from the zone, keeping their sizes balanced")
+	maxTotalCores  = flag.Int64("max-total-cores", 0, "Maximum number of cores in all node groups together. 0 means no limit.")
+	maxTotalMemory = flag.String("max-total-memory", "",
+		"Maximum amount of memory in all node groups together, as a quantity (e.g. 512Gi). Empty means no limit.")
 	scaleDownEnabled = flag.Bool("scale-down-enabled", true, "Should CA scale down the cluster")
 	scaleDownDelay   = flag.Duration("scale-down-delay", 10*time.Minute,
 		"Duration from the last scale up to the time when CA starts to check scale down options")
@@ -49,18 +65,90 @@ var (
 		"How long the node should be unneeded before it is eligible for scale down")
 	scaleDownUtilizationThreshold = flag.Float64("scale-down-utilization-threshold", 0.5,
 		"Node utilization level, defined as sum of requested resources divided by capacity, below which a node can be considered for scale down")
+	maxEmptyBulkDelete    = flag.Int("max-empty-bulk-delete", 10, "Maximum number of empty nodes that can be deleted at the same time.")
+	maxNonEmptyBulkDelete = flag.Int("max-nonempty-bulk-delete", 1,
+		"Maximum number of non-empty nodes that can be deleted in one scale down iteration. "+
+			"All of them are removed only if their pods fit on the remaining nodes.")
+	maxDrainTime = flag.Duration("max-drain-time", 2*time.Minute,
+		"Maximum time CA waits for the pods of a node being scaled down to terminate and be rescheduled "+
+			"on other nodes before the scale down is abandoned")
 	scaleDownTrialInterval = flag.Duration("scale-down-trial-interval", 1*time.Minute,
 		"How often scale down possiblity is check")
+	maxNodeProvisionTime = flag.Duration("max-node-provision-time", 15*time.Minute,
+		"Maximum time CA waits for a node requested from a node group to register. After that the node is considered failed "+
+			"and the node group is excluded from scale up for some time")
+	maxNodeUnreadyTime = flag.Duration("max-node-unready-time", 20*time.Minute,
+		"Maximum time a node of a node group can be not ready before CA deletes it")
+	maxUnreadyBulkDelete = flag.Int("max-unready-bulk-delete", 3,
+		"Maximum number of unready nodes deleted in one iteration. At most one is deleted from each node group.")
+	maxUnreadyPercentage = flag.Float64("max-unready-percentage", 33,
+		"Unready nodes are not deleted from the cluster or node group when more than this percentage of its nodes is not ready")
 	scanInterval = flag.Duration("scan-interval", 10*time.Second, "How often cluster is reevaluated for scale up or down")
+
+	estimatorFlag = flag.String("estimator", estimator.BasicEstimatorName,
+		"Type of resource estimator to be used in scale up. Available values: ["+strings.Join(estimator.AvailableEstimators, ",")+"]")
+	expanderFlag = flag.String("expander", expander.FirstExpanderName,
+		"Type of node group expander to be used in scale up. Available values: ["+strings.Join(expander.AvailableExpanders, ",")+"]")
+	expanderPriorities = flag.String("expander-priorities", "",
+		"Comma separated list of regular expressions matching node group ids, from the highest to the lowest priority. Used by the priority expander.")
+	leaderElect = flag.Bool("leader-elect", false,
+		"If enabled several CA replicas can run and only the elected leader autoscales the cluster.")
+	leaderElectNamespace = flag.String("leader-elect-namespace", "kube-system",
+		"Namespace of the endpoints object holding the leader lease.")
+	leaderElectName = flag.String("leader-elect-name", "cluster-autoscaler",
+		"Name of the endpoints object holding the leader lease.")
+	leaderElectLeaseDuration = flag.Duration("leader-elect-lease-duration", 15*time.Second,
+		"How long non-leader replicas wait after the last lease renewal before trying to take over the leadership.")
+	leaderElectRenewDeadline = flag.Duration("leader-elect-renew-deadline", 10*time.Second,
+		"How long the leader tries to renew the lease before it stops leading. Must be shorter than the lease duration.")
+	leaderElectRetryPeriod = flag.Duration("leader-elect-retry-period", 2*time.Second,
+		"How long replicas wait between attempts to acquire or renew the lease.")
+	configFile = flag.String("config-file", "",
+		"Path to a YAML or JSON file with the node groups and the tuning settings. Settings missing in the file default "+
+			"to the flag values. The file is checked for changes and a changed config is applied at the start of the next loop.")
+	whatIfSnapshot = flag.String("what-if-snapshot", "",
+		"Path to a YAML or JSON snapshot of nodes, pods and migs. If set, CA simulates a single loop on the snapshot, "+
+			"prints its decisions and exits without connecting to the cluster or the cloud provider.")
 )
 
 func main() {
 	flag.Var(&migConfigFlag, "nodes", "sets min,max size and url of a MIG to be controlled by Cluster Autoscaler. "+
-		"Can be used multiple times. Format: <min>:<max>:<migurl>")
+		"Can be used multiple times. Format: <min>:<max>:<migurl>[,<option>=<value>...]. Options override the global "+
+		"scale-down-utilization-threshold and scale-down-unneeded-time for the MIG.")
 	flag.Parse()
+	rand.Seed(time.Now().UnixNano())
+
+	autoscalerConfig, err := configFromFlags()
+	if err != nil {
+		glog.Fatalf("Invalid flags: %v", err)
+	}
+	var configWatcher *ConfigWatcher
+	if *configFile != "" {
+		configWatcher = NewConfigWatcher(*configFile, autoscalerConfig)
+		if autoscalerConfig, err = configWatcher.Load(); err != nil {
+			glog.Fatalf("Failed to load config from %s: %v", *configFile, err)
+		}
+	}
+
+	if *whatIfSnapshot != "" {
+		snapshot, err := LoadWhatIfSnapshot(*whatIfSnapshot)
+		if err != nil {
+			glog.Fatalf("Failed to load snapshot: %v", err)
+		}
+		context := AutoscalingContext{}
+		if err := setContextConfig(&context, autoscalerConfig); err != nil {
+			glog.Fatalf("Invalid config: %v", err)
+		}
+		if err := RunWhatIf(snapshot, context, os.Stdout); err != nil {
+			glog.Fatalf("What-if simulation failed: %v", err)
+		}
+		return
+	}
 
+	statusTracker := NewStatusTracker()
 	go func() {
 		http.Handle("/metrics", prometheus.Handler())
+		http.Handle("/status", statusTracker)
 		err := http.ListenAndServe(*address, nil)
 		glog.Fatalf("Failed to start metrics: %v", err)
 	}()
@@ -75,9 +163,9 @@ func main() {
 	if err != nil {
 		glog.Fatalf("Failed to build Kuberentes client configuration: %v", err)
 	}
-	migConfigs := make([]*config.MigConfig, 0, len(migConfigFlag))
-	for i := range migConfigFlag {
-		migConfigs = append(migConfigs, &migConfigFlag[i])
+	migConfigs, err := autoscalerConfig.MigConfigs()
+	if err != nil {
+		glog.Fatalf("Invalid node groups: %v", err)
 	}
 
 	// GCE Manager
@@ -95,6 +183,8 @@ func main() {
 	if err != nil {
 		glog.Fatalf("Failed to create GCE Manager: %v", err)
 	}
+	gceCloudProvider := gce.BuildGceCloudProvider(gceManager, migConfigs)
+	cloudProvider := NewInstrumentedCloudProvider(gceCloudProvider)
 
 	kubeClient := kube_client.NewOrDie(kubeConfig)
 
@@ -105,7 +195,12 @@ func main() {
 	unschedulablePodLister := NewUnschedulablePodLister(kubeClient)
 	scheduledPodLister := NewScheduledPodLister(kubeClient)
 	nodeLister := NewNodeLister(kubeClient)
+	podDisruptionBudgetLister := NewPodDisruptionBudgetLister(kubeClient)
 
+	scaleUpTracker := NewScaleUpTracker(autoscalerConfig.MaxNodeProvisionTime.Duration)
+	nodeCleaner := NewUnhealthyNodeCleaner(autoscalerConfig.MaxNodeProvisionTime.Duration,
+		autoscalerConfig.MaxNodeUnreadyTime.Duration, autoscalerConfig.MaxUnreadyBulkDelete,
+		autoscalerConfig.MaxUnreadyPercentage)
 	lastScaleUpTime := time.Now()
 	lastScaleDownFailedTrial := time.Now()
 	unneededNodes := make(map[string]time.Time)
@@ -115,13 +210,80 @@ func main() {
 	eventBroadcaster.StartRecordingToSink(kubeClient.Events(""))
 	recorder := eventBroadcaster.NewRecorder(kube_api.EventSource{Component: "cluster-autoscaler"})
 
+	autoscalingContext := &AutoscalingContext{
+		CloudProvider:      cloudProvider,
+		KubeClient:         kubeClient,
+		RequiredPodsLister: simulator.NewClientRequiredPodsLister(kubeClient),
+		Recorder:           recorder,
+		StatusTracker:      statusTracker,
+		ScaleUpTracker:     scaleUpTracker,
+		PredicateChecker:   predicateChecker,
+	}
+	if err := setContextConfig(autoscalingContext, autoscalerConfig); err != nil {
+		glog.Fatalf("Invalid config: %v", err)
+	}
+	if configWatcher != nil {
+		go configWatcher.Run(configFileCheckInterval)
+	}
+
+	if *leaderElect {
+		identity, err := os.Hostname()
+		if err != nil {
+			glog.Fatalf("Failed to get hostname: %v", err)
+		}
+		started := make(chan struct{})
+		go leaderelection.RunOrDie(leaderelection.LeaderElectionConfig{
+			EndpointsMeta: kube_api.ObjectMeta{
+				Namespace: *leaderElectNamespace,
+				Name:      *leaderElectName,
+			},
+			Identity:      identity,
+			Client:        kubeClient,
+			EventRecorder: recorder,
+			LeaseDuration: *leaderElectLeaseDuration,
+			RenewDeadline: *leaderElectRenewDeadline,
+			RetryPeriod:   *leaderElectRetryPeriod,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(_ <-chan struct{}) {
+					glog.Infof("Became leader as %s", identity)
+					leader.Set(1)
+					close(started)
+				},
+				OnStoppedLeading: func() {
+					leader.Set(0)
+					// Another replica may be autoscaling already, so the state of this one is no longer valid.
+					glog.Fatalf("Lost leader lease")
+				},
+			},
+		})
+		<-started
+	} else {
+		leader.Set(1)
+	}
+
+	if err := CleanUpCordonedNodes(kubeClient); err != nil {
+		glog.Warningf("Failed to clean up nodes cordoned by a previous run: %v", err)
+	}
+
 	for {
 		select {
-		case <-time.After(*scanInterval):
+		case <-time.After(autoscalerConfig.ScanInterval.Duration):
 			{
 				loopStart := time.Now()
 				updateLastTime("main")
 
+				if configWatcher != nil {
+					if updated, err := configWatcher.Updated(); updated != nil || err != nil {
+						if err == nil {
+							err = applyConfig(updated, autoscalingContext, gceCloudProvider, scaleUpTracker, nodeCleaner)
+						}
+						if err == nil {
+							autoscalerConfig = updated
+						}
+						recordConfigReload(recorder, *configFile, err)
+					}
+				}
+
 				nodes, err := nodeLister.List()
 				if err != nil {
 					glog.Errorf("Failed to list nodes: %v", err)
@@ -132,11 +294,28 @@ func main() {
 					continue
 				}
 
-				if err := CheckMigsAndNodes(nodes, gceManager); err != nil {
-					glog.Warningf("Cluster is not ready for autoscaling: %v", err)
+				allNodes, err := nodeLister.ListAll()
+				if err != nil {
+					glog.Errorf("Failed to list all nodes: %v", err)
 					continue
 				}
 
+				// Nodes that didn't register in time are given up so that they don't block autoscaling.
+				scaleUpTracker.CheckNodeProvisioning(cloudProvider, allNodes, loopStart)
+				nodeCleaner.CleanUp(autoscalingContext, allNodes, loopStart)
+
+				// Unhealthy node groups are left alone, the other ones are still autoscaled.
+				autoscalingContext.UnhealthyNodeGroups = make(map[string]bool)
+				for id, err := range CheckGroupsAndNodes(nodes, cloudProvider) {
+					glog.Warningf("Node group %s is not ready for autoscaling: %v", id, err)
+					autoscalingContext.UnhealthyNodeGroups[id] = true
+				}
+				// Node groups waiting for the requested nodes are left alone until the nodes register.
+				for _, id := range scaleUpTracker.PendingScaleUps() {
+					glog.V(1).Infof("Waiting for the nodes requested from %s to register", id)
+					autoscalingContext.UnhealthyNodeGroups[id] = true
+				}
+
 				allUnschedulablePods, err := unschedulablePodLister.List()
 				if err != nil {
 					glog.Errorf("Failed to list unscheduled pods: %v", err)
@@ -149,6 +328,12 @@ func main() {
 					continue
 				}
 
+				podDisruptionBudgets, err := podDisruptionBudgetLister.List()
+				if err != nil {
+					glog.Errorf("Failed to list pod disruption budgets: %v", err)
+					continue
+				}
+
 				// We need to reset all pods that have been marked as unschedulable not after
 				// the newest node became available for the scheduler.
 				allNodesAvailableTime := GetAllNodesAvailableTime(nodes)
@@ -170,7 +355,7 @@ func main() {
 				// Without below check cluster might be unnecessary scaled up to the max allowed size
 				// in the describe situation.
 				schedulablePodsPresent := false
-				if *verifyUnschedulablePods {
+				if autoscalerConfig.VerifyUnschedulablePods {
 					newUnschedulablePodsToHelp := FilterOutSchedulable(unschedulablePodsToHelp, nodes, allScheduled, predicateChecker)
 
 					if len(newUnschedulablePodsToHelp) != len(unschedulablePodsToHelp) {
@@ -180,33 +365,35 @@ func main() {
 					unschedulablePodsToHelp = newUnschedulablePodsToHelp
 				}
 
+				unschedulablePodsCount.Set(float64(len(unschedulablePodsToHelp)))
+				scaleDownAllowed := true
 				if len(unschedulablePodsToHelp) == 0 {
 					glog.V(1).Info("No unschedulable pods")
 				} else {
 					scaleUpStart := time.Now()
 					updateLastTime("scaleup")
-					scaledUp, err := ScaleUp(unschedulablePodsToHelp, nodes, migConfigs, gceManager, kubeClient, predicateChecker, recorder)
+					scaledUp, err := ScaleUp(autoscalingContext, unschedulablePodsToHelp, nodes)
 
 					updateDuration("scaleup", scaleUpStart)
 
 					if err != nil {
 						glog.Errorf("Failed to scale up: %v", err)
-						continue
+						scaleDownAllowed = false
 					} else {
 						if scaledUp {
 							lastScaleUpTime = time.Now()
 							// No scale down in this iteration.
-							continue
+							scaleDownAllowed = false
 						}
 					}
 				}
 
-				if *scaleDownEnabled {
+				if autoscalerConfig.ScaleDownEnabled && scaleDownAllowed {
 					unneededStart := time.Now()
 
 					// In dry run only utilization is updated
-					calculateUnneededOnly := lastScaleUpTime.Add(*scaleDownDelay).After(time.Now()) ||
-						lastScaleDownFailedTrial.Add(*scaleDownTrialInterval).After(time.Now()) ||
+					calculateUnneededOnly := lastScaleUpTime.Add(autoscalerConfig.ScaleDownDelay.Duration).After(time.Now()) ||
+						lastScaleDownFailedTrial.Add(autoscalerConfig.ScaleDownTrialInterval.Duration).After(time.Now()) ||
 						schedulablePodsPresent
 
 					glog.V(4).Infof("Scale down status: unneededOnly=%v lastScaleUpTime=%s "+
@@ -217,13 +404,14 @@ func main() {
 					glog.V(4).Infof("Calculating unneded nodes")
 
 					unneededNodes = FindUnneededNodes(
+						autoscalingContext,
 						nodes,
 						unneededNodes,
-						*scaleDownUtilizationThreshold,
 						allScheduled,
-						predicateChecker)
+						podDisruptionBudgets)
 
 					updateDuration("findUnneeded", unneededStart)
+					unneededNodesCount.Set(float64(len(unneededNodes)))
 
 					for key, val := range unneededNodes {
 						if glog.V(4) {
@@ -238,14 +426,18 @@ func main() {
 						updateLastTime("scaledown")
 
 						result, err := ScaleDown(
+							autoscalingContext,
 							nodes,
 							unneededNodes,
-							*scaleDownUnneededTime,
 							allScheduled,
-							gceManager, kubeClient, predicateChecker)
+							podDisruptionBudgets)
 
 						updateDuration("scaledown", scaleDownStart)
 
+						if result != ScaleDownNodeDeleted {
+							updateScaleDownSkipped(result)
+						}
+
 						// TODO: revisit result handling
 						if err != nil {
 							glog.Errorf("Failed to scale down: %v", err)
@@ -262,12 +454,121 @@ func main() {
 						}
 					}
 				}
+
+				// The status reflects the decisions made in this iteration.
+				status := statusTracker.UpdateStatus(cloudProvider, allNodes, unneededNodes, time.Now())
+				updateNodeGroupSizeMetrics(status)
+				if err := WriteStatusConfigMap(kubeClient, status); err != nil {
+					glog.Warningf("Failed to write status ConfigMap: %v", err)
+				}
 				updateDuration("main", loopStart)
 			}
 		}
 	}
 }
 
+// configFromFlags builds the config from the command line flags.
+func configFromFlags() (*config.AutoscalerConfig, error) {
+	autoscalerConfig := &config.AutoscalerConfig{
+		NodeGroups:                    make([]config.NodeGroupConfig, 0, len(migConfigFlag)),
+		ScanInterval:                  unversioned.Duration{Duration: *scanInterval},
+		VerifyUnschedulablePods:       *verifyUnschedulablePods,
+		Estimator:                     *estimatorFlag,
+		Expander:                      *expanderFlag,
+		BalanceSimilarNodeGroups:      *balanceSimilarNodeGroups,
+		MaxTotalCores:                 *maxTotalCores,
+		MaxNodeProvisionTime:          unversioned.Duration{Duration: *maxNodeProvisionTime},
+		MaxNodeUnreadyTime:            unversioned.Duration{Duration: *maxNodeUnreadyTime},
+		MaxUnreadyBulkDelete:          *maxUnreadyBulkDelete,
+		MaxUnreadyPercentage:          *maxUnreadyPercentage,
+		ScaleDownEnabled:              *scaleDownEnabled,
+		ScaleDownDelay:                unversioned.Duration{Duration: *scaleDownDelay},
+		ScaleDownTrialInterval:        unversioned.Duration{Duration: *scaleDownTrialInterval},
+		ScaleDownUnneededTime:         unversioned.Duration{Duration: *scaleDownUnneededTime},
+		ScaleDownUtilizationThreshold: *scaleDownUtilizationThreshold,
+		MaxEmptyBulkDelete:            *maxEmptyBulkDelete,
+		MaxNonEmptyBulkDelete:         *maxNonEmptyBulkDelete,
+		MaxDrainTime:                  unversioned.Duration{Duration: *maxDrainTime},
+	}
+	for i := range migConfigFlag {
+		autoscalerConfig.NodeGroups = append(autoscalerConfig.NodeGroups, migConfigFlag[i].NodeGroupConfig())
+	}
+	if *expanderPriorities != "" {
+		autoscalerConfig.ExpanderPriorities = strings.Split(*expanderPriorities, ",")
+	}
+	if *maxTotalMemory != "" {
+		quantity, err := resource.ParseQuantity(*maxTotalMemory)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse max total memory: %v", err)
+		}
+		autoscalerConfig.MaxTotalMemory = quantity
+	}
+	return autoscalerConfig, nil
+}
+
+// setContextConfig sets the settings of the autoscaling context from the config. The context is
+// not modified if the config is invalid.
+func setContextConfig(context *AutoscalingContext, autoscalerConfig *config.AutoscalerConfig) error {
+	expanderStrategy, err := expander.ExpanderStrategyFromString(autoscalerConfig.Expander, autoscalerConfig.ExpanderPriorities)
+	if err != nil {
+		return fmt.Errorf("failed to create expander: %v", err)
+	}
+	if !isValidEstimator(autoscalerConfig.Estimator) {
+		return fmt.Errorf("unrecognized estimator: %s", autoscalerConfig.Estimator)
+	}
+	migConfigs, err := autoscalerConfig.MigConfigs()
+	if err != nil {
+		return err
+	}
+	nodeGroupScaleDownOptions := make(map[string]NodeGroupScaleDownOptions)
+	for _, migConfig := range migConfigs {
+		nodeGroupScaleDownOptions[migConfig.Url()] = NodeGroupScaleDownOptions{
+			ScaleDownUtilizationThreshold: migConfig.ScaleDownUtilizationThreshold,
+			ScaleDownUnneededTime:         migConfig.ScaleDownUnneededTime,
+		}
+	}
+
+	context.ExpanderStrategy = expanderStrategy
+	context.EstimatorName = autoscalerConfig.Estimator
+	context.BalanceSimilarNodeGroups = autoscalerConfig.BalanceSimilarNodeGroups
+	context.MaxTotalCores = autoscalerConfig.MaxTotalCores
+	context.MaxTotalMemory = autoscalerConfig.MaxTotalMemory.Value()
+	context.ScaleDownUtilizationThreshold = autoscalerConfig.ScaleDownUtilizationThreshold
+	context.ScaleDownUnneededTime = autoscalerConfig.ScaleDownUnneededTime.Duration
+	context.NodeGroupScaleDownOptions = nodeGroupScaleDownOptions
+	context.MaxEmptyBulkDelete = autoscalerConfig.MaxEmptyBulkDelete
+	context.MaxNonEmptyBulkDelete = autoscalerConfig.MaxNonEmptyBulkDelete
+	context.MaxDrainTime = autoscalerConfig.MaxDrainTime.Duration
+	return nil
+}
+
+// applyConfig switches the running cluster autoscaler to the config. Nothing is changed if the
+// config is invalid.
+func applyConfig(autoscalerConfig *config.AutoscalerConfig, context *AutoscalingContext, gceCloudProvider *gce.GceCloudProvider,
+	scaleUpTracker *ScaleUpTracker, nodeCleaner *UnhealthyNodeCleaner) error {
+	migConfigs, err := autoscalerConfig.MigConfigs()
+	if err != nil {
+		return err
+	}
+	if err := setContextConfig(context, autoscalerConfig); err != nil {
+		return err
+	}
+	gceCloudProvider.SetMigConfigs(migConfigs)
+	scaleUpTracker.SetMaxNodeProvisionTime(autoscalerConfig.MaxNodeProvisionTime.Duration)
+	nodeCleaner.SetTimeouts(autoscalerConfig.MaxNodeProvisionTime.Duration, autoscalerConfig.MaxNodeUnreadyTime.Duration)
+	nodeCleaner.SetUnreadyLimits(autoscalerConfig.MaxUnreadyBulkDelete, autoscalerConfig.MaxUnreadyPercentage)
+	return nil
+}
+
+func isValidEstimator(name string) bool {
+	for _, estimatorName := range estimator.AvailableEstimators {
+		if estimatorName == name {
+			return true
+		}
+	}
+	return false
+}
+
 func updateDuration(label string, start time.Time) {
 	duration.WithLabelValues(label).Observe(durationToMicro(start))
 	lastDuration.WithLabelValues(label).Set(durationToMicro(start))
@@ -0,0 +1,187 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io"
+
+	"k8s.io/kubernetes/pkg/api/resource"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/util/yaml"
+)
+
+// AutoscalerConfig contains the node groups and the tuning settings of the cluster autoscaler.
+// It can be loaded from a YAML or JSON file, in which case the settings missing in the file
+// keep their flag values. Durations are strings like "10m".
+type AutoscalerConfig struct {
+	// NodeGroups are the MIGs controlled by the cluster autoscaler.
+	NodeGroups []NodeGroupConfig `json:"nodeGroups"`
+
+	ScanInterval             unversioned.Duration `json:"scanInterval"`
+	VerifyUnschedulablePods  bool                 `json:"verifyUnschedulablePods"`
+	Estimator                string               `json:"estimator"`
+	Expander                 string               `json:"expander"`
+	ExpanderPriorities       []string             `json:"expanderPriorities"`
+	BalanceSimilarNodeGroups bool                 `json:"balanceSimilarNodeGroups"`
+	MaxTotalCores            int64                `json:"maxTotalCores"`
+	MaxTotalMemory           resource.Quantity    `json:"maxTotalMemory"`
+	MaxNodeProvisionTime     unversioned.Duration `json:"maxNodeProvisionTime"`
+	MaxNodeUnreadyTime       unversioned.Duration `json:"maxNodeUnreadyTime"`
+	MaxUnreadyBulkDelete     int                  `json:"maxUnreadyBulkDelete"`
+	MaxUnreadyPercentage     float64              `json:"maxUnreadyPercentage"`
+
+	ScaleDownEnabled              bool                 `json:"scaleDownEnabled"`
+	ScaleDownDelay                unversioned.Duration `json:"scaleDownDelay"`
+	ScaleDownTrialInterval        unversioned.Duration `json:"scaleDownTrialInterval"`
+	ScaleDownUnneededTime         unversioned.Duration `json:"scaleDownUnneededTime"`
+	ScaleDownUtilizationThreshold float64              `json:"scaleDownUtilizationThreshold"`
+	MaxEmptyBulkDelete            int                  `json:"maxEmptyBulkDelete"`
+	MaxNonEmptyBulkDelete         int                  `json:"maxNonEmptyBulkDelete"`
+	MaxDrainTime                  unversioned.Duration `json:"maxDrainTime"`
+}
+
+// NodeGroupConfig describes a MIG controlled by the cluster autoscaler. Unset scale down settings
+// mean that the global ones are used.
+type NodeGroupConfig struct {
+	MinSize                       int                   `json:"minSize"`
+	MaxSize                       int                   `json:"maxSize"`
+	Url                           string                `json:"url"`
+	ScaleDownUtilizationThreshold *float64              `json:"scaleDownUtilizationThreshold,omitempty"`
+	ScaleDownUnneededTime         *unversioned.Duration `json:"scaleDownUnneededTime,omitempty"`
+}
+
+// LoadAutoscalerConfig reads the config from a YAML or JSON stream on top of the defaults and
+// validates it. Node groups and expander priorities in the stream replace the default ones as
+// a whole. The defaults are not modified.
+func LoadAutoscalerConfig(reader io.Reader, defaults *AutoscalerConfig) (*AutoscalerConfig, error) {
+	config := *defaults
+	// Decoding into a non-empty slice would merge the decoded elements into the default ones.
+	config.NodeGroups = nil
+	config.ExpanderPriorities = nil
+	if err := yaml.NewYAMLOrJSONDecoder(reader, 4096).Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %v", err)
+	}
+	if config.NodeGroups == nil {
+		config.NodeGroups = append([]NodeGroupConfig(nil), defaults.NodeGroups...)
+	}
+	if config.ExpanderPriorities == nil {
+		config.ExpanderPriorities = append([]string(nil), defaults.ExpanderPriorities...)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// Validate checks that the node groups and the settings are correct. The estimator and expander
+// names are checked when they are built.
+func (config *AutoscalerConfig) Validate() error {
+	if _, err := config.MigConfigs(); err != nil {
+		return err
+	}
+	if config.ScanInterval.Duration <= 0 {
+		return fmt.Errorf("scan interval must be positive")
+	}
+	if config.ScaleDownUtilizationThreshold < 0 || config.ScaleDownUtilizationThreshold > 1 {
+		return fmt.Errorf("scale down utilization threshold must be in [0, 1]")
+	}
+	if config.MaxTotalCores < 0 {
+		return fmt.Errorf("max total cores must be >= 0")
+	}
+	if config.MaxTotalMemory.Value() < 0 {
+		return fmt.Errorf("max total memory must be >= 0")
+	}
+	if config.MaxUnreadyPercentage < 0 || config.MaxUnreadyPercentage > 100 {
+		return fmt.Errorf("max unready percentage must be in [0, 100]")
+	}
+	if config.MaxEmptyBulkDelete < 0 || config.MaxNonEmptyBulkDelete < 0 || config.MaxUnreadyBulkDelete < 0 {
+		return fmt.Errorf("max bulk deletes must be >= 0")
+	}
+	durations := map[string]unversioned.Duration{
+		"max node provision time":   config.MaxNodeProvisionTime,
+		"max node unready time":     config.MaxNodeUnreadyTime,
+		"scale down delay":          config.ScaleDownDelay,
+		"scale down trial interval": config.ScaleDownTrialInterval,
+		"scale down unneeded time":  config.ScaleDownUnneededTime,
+		"max drain time":            config.MaxDrainTime,
+	}
+	for name, duration := range durations {
+		if duration.Duration < 0 {
+			return fmt.Errorf("%s must be >= 0", name)
+		}
+	}
+	return nil
+}
+
+// MigConfigs builds MigConfig for each node group.
+func (config *AutoscalerConfig) MigConfigs() ([]*MigConfig, error) {
+	result := make([]*MigConfig, 0, len(config.NodeGroups))
+	urls := make(map[string]bool)
+	for _, nodeGroup := range config.NodeGroups {
+		migconfig, err := nodeGroup.MigConfig()
+		if err != nil {
+			return nil, err
+		}
+		if urls[migconfig.Url()] {
+			return nil, fmt.Errorf("mig %s configured more than once", migconfig.Url())
+		}
+		urls[migconfig.Url()] = true
+		result = append(result, migconfig)
+	}
+	return result, nil
+}
+
+// MigConfig builds MigConfig for the node group.
+func (nodeGroup *NodeGroupConfig) MigConfig() (*MigConfig, error) {
+	migconfig, err := NewMigConfig(nodeGroup.MinSize, nodeGroup.MaxSize, nodeGroup.Url)
+	if err != nil {
+		return nil, err
+	}
+	if threshold := nodeGroup.ScaleDownUtilizationThreshold; threshold != nil {
+		if *threshold < 0 || *threshold > 1 {
+			return nil, fmt.Errorf("scale down utilization threshold of %s must be in [0, 1]", nodeGroup.Url)
+		}
+		value := *threshold
+		migconfig.ScaleDownUtilizationThreshold = &value
+	}
+	if unneededTime := nodeGroup.ScaleDownUnneededTime; unneededTime != nil {
+		if unneededTime.Duration < 0 {
+			return nil, fmt.Errorf("scale down unneeded time of %s must not be negative", nodeGroup.Url)
+		}
+		value := unneededTime.Duration
+		migconfig.ScaleDownUnneededTime = &value
+	}
+	return migconfig, nil
+}
+
+// NodeGroupConfig returns the node group config equivalent to the MigConfig.
+func (migconfig *MigConfig) NodeGroupConfig() NodeGroupConfig {
+	nodeGroup := NodeGroupConfig{
+		MinSize: migconfig.MinSize,
+		MaxSize: migconfig.MaxSize,
+		Url:     migconfig.Url(),
+	}
+	if migconfig.ScaleDownUtilizationThreshold != nil {
+		threshold := *migconfig.ScaleDownUtilizationThreshold
+		nodeGroup.ScaleDownUtilizationThreshold = &threshold
+	}
+	if migconfig.ScaleDownUnneededTime != nil {
+		nodeGroup.ScaleDownUnneededTime = &unversioned.Duration{Duration: *migconfig.ScaleDownUnneededTime}
+	}
+	return nodeGroup
+}
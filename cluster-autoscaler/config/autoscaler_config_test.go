@@ -0,0 +1,157 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/kubernetes/pkg/api/unversioned"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMigUrl = "https://content.googleapis.com/compute/v1/projects/test-project/zones/test-zone/instanceGroups/test-name"
+
+func testDefaults() *AutoscalerConfig {
+	return &AutoscalerConfig{
+		NodeGroups:                    []NodeGroupConfig{{MinSize: 1, MaxSize: 5, Url: testMigUrl}},
+		ScanInterval:                  unversioned.Duration{Duration: 10 * time.Second},
+		Estimator:                     "binpacking",
+		Expander:                      "random",
+		ScaleDownEnabled:              true,
+		ScaleDownUnneededTime:         unversioned.Duration{Duration: 10 * time.Minute},
+		ScaleDownUtilizationThreshold: 0.5,
+		MaxEmptyBulkDelete:            10,
+		MaxNonEmptyBulkDelete:         1,
+	}
+}
+
+func TestLoadAutoscalerConfig(t *testing.T) {
+	defaults := testDefaults()
+	config, err := LoadAutoscalerConfig(strings.NewReader(`
+nodeGroups:
+- minSize: 0
+  maxSize: 20
+  url: `+testMigUrl+`
+  scaleDownUtilizationThreshold: 0.7
+  scaleDownUnneededTime: 2m
+scaleDownEnabled: false
+maxTotalMemory: 512Gi
+expander: priority
+expanderPriorities: [".*"]
+`), defaults)
+	assert.NoError(t, err)
+
+	migConfigs, err := config.MigConfigs()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(migConfigs))
+	assert.Equal(t, 0, migConfigs[0].MinSize)
+	assert.Equal(t, 20, migConfigs[0].MaxSize)
+	assert.Equal(t, "test-name", migConfigs[0].Name)
+	assert.Equal(t, 0.7, *migConfigs[0].ScaleDownUtilizationThreshold)
+	assert.Equal(t, 2*time.Minute, *migConfigs[0].ScaleDownUnneededTime)
+	assert.False(t, config.ScaleDownEnabled)
+	assert.Equal(t, int64(512*1024*1024*1024), config.MaxTotalMemory.Value())
+	assert.Equal(t, "priority", config.Expander)
+	assert.Equal(t, []string{".*"}, config.ExpanderPriorities)
+
+	// Settings missing in the file keep the defaults, which are not modified.
+	assert.Equal(t, 10*time.Second, config.ScanInterval.Duration)
+	assert.Equal(t, "binpacking", config.Estimator)
+	assert.Equal(t, 0.5, config.ScaleDownUtilizationThreshold)
+	assert.Equal(t, 5, defaults.NodeGroups[0].MaxSize)
+	assert.True(t, defaults.ScaleDownEnabled)
+
+	config, err = LoadAutoscalerConfig(strings.NewReader(`{"scaleDownUnneededTime": "1m"}`), defaults)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, config.ScaleDownUnneededTime.Duration)
+	assert.Equal(t, defaults.NodeGroups, config.NodeGroups)
+}
+
+func TestLoadAutoscalerConfigWithNodesFlag(t *testing.T) {
+	var nodesFlag MigConfigFlag
+	assert.NoError(t, nodesFlag.Set("2:10:"+testMigUrl+",scale-down-utilization-threshold=0.3"))
+	defaults := testDefaults()
+	defaults.NodeGroups = []NodeGroupConfig{nodesFlag[0].NodeGroupConfig()}
+	otherMigUrl := strings.Replace(testMigUrl, "test-name", "other-name", 1)
+
+	// Node groups in the file replace the ones from the flags, nothing is inherited by index.
+	config, err := LoadAutoscalerConfig(strings.NewReader(`
+nodeGroups:
+- maxSize: 5
+  url: `+otherMigUrl+`
+`), defaults)
+	assert.NoError(t, err)
+	assert.Equal(t, []NodeGroupConfig{{MinSize: 0, MaxSize: 5, Url: otherMigUrl}}, config.NodeGroups)
+
+	// Without node groups in the file the ones from the flags are used.
+	config, err = LoadAutoscalerConfig(strings.NewReader(`scaleDownEnabled: false`), defaults)
+	assert.NoError(t, err)
+	assert.Equal(t, defaults.NodeGroups, config.NodeGroups)
+	assert.Equal(t, 2, config.NodeGroups[0].MinSize)
+	assert.Equal(t, 0.3, *config.NodeGroups[0].ScaleDownUtilizationThreshold)
+}
+
+func TestLoadAutoscalerConfigInvalid(t *testing.T) {
+	for _, content := range []string{
+		"scanInterval: 0s",
+		"scaleDownUtilizationThreshold: 1.5",
+		"maxTotalCores: -1",
+		"maxDrainTime: -1m",
+		"scaleDownDelay: abc",
+		"nodeGroups:\n- minSize: 3\n  maxSize: 2\n  url: " + testMigUrl,
+		"nodeGroups: [{minSize: 1, maxSize: 2, url: x}]",
+		"nodeGroups:\n- minSize: 1\n  maxSize: 2\n  url: " + testMigUrl + "\n  scaleDownUtilizationThreshold: 2",
+		"nodeGroups:\n- minSize: 1\n  maxSize: 2\n  url: " + testMigUrl + "\n- minSize: 1\n  maxSize: 3\n  url: " + testMigUrl,
+	} {
+		_, err := LoadAutoscalerConfig(strings.NewReader(content), testDefaults())
+		if assert.Error(t, err, content) {
+			assert.NotContains(t, err.Error(), "yaml:", content)
+		}
+	}
+}
+
+func TestLoadAutoscalerConfigZeroOverrides(t *testing.T) {
+	config, err := LoadAutoscalerConfig(strings.NewReader(`
+nodeGroups:
+- minSize: 1
+  maxSize: 2
+  url: `+testMigUrl+`
+  scaleDownUtilizationThreshold: 0
+  scaleDownUnneededTime: 0s
+`), testDefaults())
+	assert.NoError(t, err)
+
+	migConfigs, err := config.MigConfigs()
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, *migConfigs[0].ScaleDownUtilizationThreshold)
+	assert.Equal(t, time.Duration(0), *migConfigs[0].ScaleDownUnneededTime)
+}
+
+func TestNodeGroupConfig(t *testing.T) {
+	migConfig, err := NewMigConfig(1, 2, testMigUrl)
+	assert.NoError(t, err)
+	unneededTime := time.Minute
+	migConfig.ScaleDownUnneededTime = &unneededTime
+
+	nodeGroup := migConfig.NodeGroupConfig()
+	converted, err := nodeGroup.MigConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, migConfig, converted)
+}
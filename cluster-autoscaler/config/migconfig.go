@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	gceurl "k8s.io/contrib/cluster-autoscaler/utils/gce_url"
 	kube_api "k8s.io/kubernetes/pkg/api"
@@ -55,6 +56,12 @@ type MigConfig struct {
 	Project string
 	Zone    string
 	Name    string
+	// ScaleDownUtilizationThreshold overrides the global scale down utilization threshold for
+	// the MIG. nil means the global value is used.
+	ScaleDownUtilizationThreshold *float64
+	// ScaleDownUnneededTime overrides the global scale down unneeded time for the MIG. nil means
+	// the global value is used.
+	ScaleDownUnneededTime *time.Duration
 }
 
 // Url builds GCE url for the MIG.
@@ -80,36 +87,85 @@ func (migconfigflag *MigConfigFlag) String() string {
 	return "[" + strings.Join(configs, " ") + "]"
 }
 
-// Set adds a new configuration.
+// Set adds a new configuration, in the form <min>:<max>:<migurl>[,<option>=<value>...]. The supported
+// options are scale-down-utilization-threshold and scale-down-unneeded-time.
 func (migconfigflag *MigConfigFlag) Set(value string) error {
-	tokens := strings.SplitN(value, ":", 3)
+	options := strings.Split(value, ",")
+	tokens := strings.SplitN(options[0], ":", 3)
 	if len(tokens) != 3 {
 		return fmt.Errorf("wrong nodes configuration: %s", value)
 	}
-	migconfig := MigConfig{}
-	if size, err := strconv.Atoi(tokens[0]); err == nil {
-		if size <= 0 {
-			return fmt.Errorf("min size must be >= 1")
-		}
-		migconfig.MinSize = size
-	} else {
+	minSize, err := strconv.Atoi(tokens[0])
+	if err != nil {
 		return fmt.Errorf("failed to set min size: %s, expected integer", tokens[0])
 	}
+	maxSize, err := strconv.Atoi(tokens[1])
+	if err != nil {
+		return fmt.Errorf("failed to set max size: %s, expected integer", tokens[1])
+	}
+	migconfig, err := NewMigConfig(minSize, maxSize, tokens[2])
+	if err != nil {
+		return err
+	}
 
-	if size, err := strconv.Atoi(tokens[1]); err == nil {
-		if size < migconfig.MinSize {
-			return fmt.Errorf("max size must be greater or equal to min size")
+	for _, option := range options[1:] {
+		if err := migconfig.setOption(option); err != nil {
+			return err
 		}
-		migconfig.MaxSize = size
-	} else {
-		return fmt.Errorf("failed to set max size: %s, expected integer", tokens[1])
 	}
 
+	*migconfigflag = append(*migconfigflag, *migconfig)
+	return nil
+}
+
+// NewMigConfig builds MigConfig for the MIG with the given url, checking the sizes.
+func NewMigConfig(minSize, maxSize int, url string) (*MigConfig, error) {
+	if minSize < 0 {
+		return nil, fmt.Errorf("min size must be >= 0")
+	}
+	if maxSize < minSize {
+		return nil, fmt.Errorf("max size must be greater or equal to min size")
+	}
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("max size must be >= 1")
+	}
+	migconfig := &MigConfig{
+		MinSize: minSize,
+		MaxSize: maxSize,
+	}
 	var err error
-	if migconfig.Project, migconfig.Zone, migconfig.Name, err = gceurl.ParseMigUrl(tokens[2]); err != nil {
-		return fmt.Errorf("failed to parse mig url: %s got error: %v", tokens[2], err)
+	if migconfig.Project, migconfig.Zone, migconfig.Name, err = gceurl.ParseMigUrl(url); err != nil {
+		return nil, fmt.Errorf("failed to parse mig url: %s got error: %v", url, err)
 	}
+	return migconfig, nil
+}
 
-	*migconfigflag = append(*migconfigflag, migconfig)
+func (migconfig *MigConfig) setOption(option string) error {
+	keyValue := strings.SplitN(option, "=", 2)
+	if len(keyValue) != 2 {
+		return fmt.Errorf("wrong mig option: %s, expected <option>=<value>", option)
+	}
+	switch keyValue[0] {
+	case "scale-down-utilization-threshold":
+		threshold, err := strconv.ParseFloat(keyValue[1], 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse scale down utilization threshold: %s, expected number", keyValue[1])
+		}
+		if threshold < 0 || threshold > 1 {
+			return fmt.Errorf("scale down utilization threshold must be in [0, 1]")
+		}
+		migconfig.ScaleDownUtilizationThreshold = &threshold
+	case "scale-down-unneeded-time":
+		unneededTime, err := time.ParseDuration(keyValue[1])
+		if err != nil {
+			return fmt.Errorf("failed to parse scale down unneeded time: %s, expected duration", keyValue[1])
+		}
+		if unneededTime < 0 {
+			return fmt.Errorf("scale down unneeded time must not be negative")
+		}
+		migconfig.ScaleDownUnneededTime = &unneededTime
+	default:
+		return fmt.Errorf("unknown mig option: %s", keyValue[0])
+	}
 	return nil
 }
@@ -18,6 +18,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -28,6 +29,8 @@ func TestSet(t *testing.T) {
 	assert.Error(t, migConfigFlag.Set("a:b:c"))
 	assert.Error(t, migConfigFlag.Set("1:2:x"))
 	assert.Error(t, migConfigFlag.Set("1:2:"))
+	assert.Error(t, migConfigFlag.Set("-1:2:https://content.googleapis.com/compute/v1/projects/test-project/zones/test-zone/instanceGroups/test-name"))
+	assert.Error(t, migConfigFlag.Set("0:0:https://content.googleapis.com/compute/v1/projects/test-project/zones/test-zone/instanceGroups/test-name"))
 	assert.NoError(t, migConfigFlag.Set("111:222:https://content.googleapis.com/compute/v1/projects/test-project/zones/test-zone/instanceGroups/test-name"))
 	assert.Equal(t, 111, migConfigFlag[0].MinSize)
 	assert.Equal(t, 222, migConfigFlag[0].MaxSize)
@@ -37,4 +40,35 @@ func TestSet(t *testing.T) {
 	assert.Contains(t, migConfigFlag.String(), "222")
 	assert.Contains(t, migConfigFlag.String(), "test-zone")
 	assert.Contains(t, migConfigFlag.String(), "test-name")
+
+	// Migs can be scaled to zero.
+	zeroMigConfigFlag := MigConfigFlag{}
+	assert.NoError(t, zeroMigConfigFlag.Set("0:2:https://content.googleapis.com/compute/v1/projects/test-project/zones/test-zone/instanceGroups/test-name"))
+	assert.Equal(t, 0, zeroMigConfigFlag[0].MinSize)
+}
+
+func TestSetOptions(t *testing.T) {
+	url := "https://content.googleapis.com/compute/v1/projects/test-project/zones/test-zone/instanceGroups/test-name"
+	migConfigFlag := MigConfigFlag{}
+	assert.Error(t, migConfigFlag.Set("1:2:"+url+",x"))
+	assert.Error(t, migConfigFlag.Set("1:2:"+url+",unknown=1"))
+	assert.Error(t, migConfigFlag.Set("1:2:"+url+",scale-down-utilization-threshold=1.5"))
+	assert.Error(t, migConfigFlag.Set("1:2:"+url+",scale-down-utilization-threshold=abc"))
+	assert.Error(t, migConfigFlag.Set("1:2:"+url+",scale-down-unneeded-time=-1m"))
+	assert.Error(t, migConfigFlag.Set("1:2:"+url+",scale-down-unneeded-time=10"))
+	assert.Equal(t, 0, len(migConfigFlag))
+
+	assert.NoError(t, migConfigFlag.Set("1:2:"+url+",scale-down-utilization-threshold=0.7,scale-down-unneeded-time=2m"))
+	assert.Equal(t, "test-name", migConfigFlag[0].Name)
+	assert.Equal(t, 0.7, *migConfigFlag[0].ScaleDownUtilizationThreshold)
+	assert.Equal(t, 2*time.Minute, *migConfigFlag[0].ScaleDownUnneededTime)
+
+	assert.NoError(t, migConfigFlag.Set("1:2:"+url))
+	assert.Nil(t, migConfigFlag[1].ScaleDownUtilizationThreshold)
+	assert.Nil(t, migConfigFlag[1].ScaleDownUnneededTime)
+
+	// Explicit zeros override the global settings too.
+	assert.NoError(t, migConfigFlag.Set("1:2:"+url+",scale-down-utilization-threshold=0,scale-down-unneeded-time=0s"))
+	assert.Equal(t, 0.0, *migConfigFlag[2].ScaleDownUtilizationThreshold)
+	assert.Equal(t, time.Duration(0), *migConfigFlag[2].ScaleDownUnneededTime)
 }
@@ -0,0 +1,117 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"k8s.io/contrib/cluster-autoscaler/config"
+	kube_api "k8s.io/kubernetes/pkg/api"
+	kube_record "k8s.io/kubernetes/pkg/client/record"
+
+	"github.com/golang/glog"
+)
+
+// ConfigWatcher periodically reads the cluster autoscaler config file and loads it when its
+// content changes. The loaded config, or the reason why it couldn't be loaded, is kept until
+// the main loop picks it up.
+type ConfigWatcher struct {
+	sync.Mutex
+	path     string
+	defaults *config.AutoscalerConfig
+
+	lastContent []byte
+	updated     *config.AutoscalerConfig
+	updateErr   error
+}
+
+// NewConfigWatcher builds new ConfigWatcher. Settings missing in the file are taken from defaults.
+func NewConfigWatcher(path string, defaults *config.AutoscalerConfig) *ConfigWatcher {
+	return &ConfigWatcher{
+		path:     path,
+		defaults: defaults,
+	}
+}
+
+// Load reads and validates the config file.
+func (watcher *ConfigWatcher) Load() (*config.AutoscalerConfig, error) {
+	content, err := ioutil.ReadFile(watcher.path)
+	if err != nil {
+		return nil, err
+	}
+	watcher.Lock()
+	watcher.lastContent = content
+	watcher.Unlock()
+	return config.LoadAutoscalerConfig(bytes.NewReader(content), watcher.defaults)
+}
+
+// Run checks the config file for changes every period.
+func (watcher *ConfigWatcher) Run(period time.Duration) {
+	for {
+		time.Sleep(period)
+		watcher.check()
+	}
+}
+
+// Updated returns the config loaded since the last call, or the error if the changed file was
+// invalid. Both are nil if the file didn't change.
+func (watcher *ConfigWatcher) Updated() (*config.AutoscalerConfig, error) {
+	watcher.Lock()
+	defer watcher.Unlock()
+	updated, err := watcher.updated, watcher.updateErr
+	watcher.updated, watcher.updateErr = nil, nil
+	return updated, err
+}
+
+// check loads the config file if its content changed. Each content is loaded only once, so an
+// invalid config is reported once.
+func (watcher *ConfigWatcher) check() {
+	content, err := ioutil.ReadFile(watcher.path)
+	if err != nil {
+		glog.Errorf("Failed to read config from %s: %v", watcher.path, err)
+		return
+	}
+	watcher.Lock()
+	defer watcher.Unlock()
+	if bytes.Equal(content, watcher.lastContent) {
+		return
+	}
+	watcher.lastContent = content
+	watcher.updated, watcher.updateErr = config.LoadAutoscalerConfig(bytes.NewReader(content), watcher.defaults)
+}
+
+// recordConfigReload records an event on the status ConfigMap about a config reload, with err
+// being the reason of a failure.
+func recordConfigReload(recorder kube_record.EventRecorder, path string, err error) {
+	reference := &kube_api.ObjectReference{
+		Kind:       "ConfigMap",
+		APIVersion: "v1",
+		Namespace:  StatusConfigMapNamespace,
+		Name:       StatusConfigMapName,
+	}
+	if err != nil {
+		glog.Errorf("Failed to reload config from %s: %v", path, err)
+		recorder.Eventf(reference, kube_api.EventTypeWarning, "ConfigReloadFailed",
+			"failed to reload config from %s: %v", path, err)
+		return
+	}
+	glog.V(1).Infof("Reloaded config from %s", path)
+	recorder.Eventf(reference, kube_api.EventTypeNormal, "ConfigReloaded", "reloaded config from %s", path)
+}
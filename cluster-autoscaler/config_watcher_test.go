@@ -0,0 +1,84 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/contrib/cluster-autoscaler/config"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	kube_record "k8s.io/kubernetes/pkg/client/record"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-watcher")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("scaleDownUtilizationThreshold: 0.6\n"), 0644))
+
+	defaults := &config.AutoscalerConfig{
+		ScanInterval:                  unversioned.Duration{Duration: 10 * time.Second},
+		ScaleDownUtilizationThreshold: 0.5,
+	}
+	watcher := NewConfigWatcher(path, defaults)
+	loaded, err := watcher.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, 0.6, loaded.ScaleDownUtilizationThreshold)
+
+	// The loaded content is not reported as an update.
+	watcher.check()
+	updated, err := watcher.Updated()
+	assert.Nil(t, updated)
+	assert.NoError(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("scaleDownUtilizationThreshold: 0.7\n"), 0644))
+	watcher.check()
+	updated, err = watcher.Updated()
+	assert.NoError(t, err)
+	assert.Equal(t, 0.7, updated.ScaleDownUtilizationThreshold)
+	updated, err = watcher.Updated()
+	assert.Nil(t, updated)
+	assert.NoError(t, err)
+
+	// Invalid content is reported once.
+	assert.NoError(t, ioutil.WriteFile(path, []byte("scaleDownUtilizationThreshold: 7\n"), 0644))
+	watcher.check()
+	updated, err = watcher.Updated()
+	assert.Nil(t, updated)
+	assert.Error(t, err)
+	watcher.check()
+	updated, err = watcher.Updated()
+	assert.Nil(t, updated)
+	assert.NoError(t, err)
+}
+
+func TestRecordConfigReload(t *testing.T) {
+	recorder := kube_record.NewFakeRecorder(10)
+	recordConfigReload(recorder, "/etc/config.yaml", nil)
+	recordConfigReload(recorder, "/etc/config.yaml", fmt.Errorf("bad threshold"))
+
+	assert.Equal(t, "Normal ConfigReloaded reloaded config from /etc/config.yaml", <-recorder.Events)
+	assert.Equal(t, "Warning ConfigReloadFailed failed to reload config from /etc/config.yaml: bad threshold", <-recorder.Events)
+}
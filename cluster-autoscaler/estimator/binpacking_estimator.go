@@ -0,0 +1,185 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package estimator
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+
+	"k8s.io/contrib/cluster-autoscaler/simulator"
+	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
+)
+
+// podInfo contains a pod and its score used for sorting in bin-packing.
+type podInfo struct {
+	score float64
+	pod   *kube_api.Pod
+}
+
+type byScoreDesc []*podInfo
+
+func (a byScoreDesc) Len() int           { return len(a) }
+func (a byScoreDesc) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byScoreDesc) Less(i, j int) bool { return a[i].score > a[j].score }
+
+// BinpackingNodeEstimator estimates the number of needed nodes to handle the given amount of pods.
+// Unlike BasicNodeEstimator it simulates scheduling of the pods on the new nodes so the estimate
+// accounts for pods that don't pack well.
+type BinpackingNodeEstimator struct {
+	predicateChecker *simulator.PredicateChecker
+	pods             []*kube_api.Pod
+	unfitting        []*kube_api.Pod
+}
+
+// NewBinpackingNodeEstimator builds a new BinpackingNodeEstimator.
+func NewBinpackingNodeEstimator(predicateChecker *simulator.PredicateChecker) *BinpackingNodeEstimator {
+	return &BinpackingNodeEstimator{
+		predicateChecker: predicateChecker,
+		pods:             make([]*kube_api.Pod, 0),
+		unfitting:        make([]*kube_api.Pod, 0),
+	}
+}
+
+// Add adds Pod to the estimation.
+func (estimator *BinpackingNodeEstimator) Add(pod *kube_api.Pod) error {
+	estimator.pods = append(estimator.pods, pod)
+	return nil
+}
+
+// Estimate implements First Fit Decreasing bin-packing approximation algorithm.
+// See https://en.wikipedia.org/wiki/Bin_packing_problem for more details.
+// While it is a multi-dimensional bin packing (cpu, mem, ports) in most cases the main dimension
+// will be cpu thus the estimated overprovisioning of 11/9 * optimal + 6/9 should be
+// still be maintained.
+// It is assumed that all pods from the given list can fit to nodeTemplate.
+// Returns the number of nodes needed to accommodate all pods from the list and a report.
+func (estimator *BinpackingNodeEstimator) Estimate(nodeTemplate *schedulercache.NodeInfo) (int, string) {
+	newNodes, _ := estimator.binpack(nodeTemplate, math.MaxInt32)
+
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("Needed nodes according to bin-packing: %d\n", len(newNodes)))
+	for i, nodeInfo := range newNodes {
+		buffer.WriteString(fmt.Sprintf("Node %d: %d pods\n", i, len(nodeInfo.Pods())-len(nodeTemplate.Pods())))
+	}
+	if len(estimator.unfitting) > 0 {
+		buffer.WriteString(fmt.Sprintf("Pods not fitting an empty node: %d\n", len(estimator.unfitting)))
+	}
+	return len(newNodes), buffer.String()
+}
+
+// PodsNotFitting returns the pods that are left without a node when they are packed like in
+// Estimate, but on at most maxNodes new nodes.
+func (estimator *BinpackingNodeEstimator) PodsNotFitting(nodeTemplate *schedulercache.NodeInfo, maxNodes int) []*kube_api.Pod {
+	_, notFitting := estimator.binpack(nodeTemplate, maxNodes)
+	return notFitting
+}
+
+// binpack places the pods on at most maxNodes new nodes using First Fit Decreasing. It returns
+// the new nodes and the pods that couldn't be placed on them.
+func (estimator *BinpackingNodeEstimator) binpack(nodeTemplate *schedulercache.NodeInfo,
+	maxNodes int) ([]*schedulercache.NodeInfo, []*kube_api.Pod) {
+	podInfos := calculatePodScore(estimator.pods, nodeTemplate.Node())
+	sort.Stable(byScoreDesc(podInfos))
+
+	estimator.unfitting = make([]*kube_api.Pod, 0)
+	notFitting := make([]*kube_api.Pod, 0)
+	newNodes := make([]*schedulercache.NodeInfo, 0)
+	for _, podInfo := range podInfos {
+		found := false
+		for i, nodeInfo := range newNodes {
+			if err := estimator.predicateChecker.CheckPredicates(podInfo.pod, nodeInfo); err == nil {
+				found = true
+				newNodes[i] = nodeWithPod(nodeInfo, podInfo.pod)
+				break
+			}
+		}
+		if !found {
+			if err := estimator.predicateChecker.CheckPredicates(podInfo.pod, nodeTemplate); err != nil {
+				estimator.unfitting = append(estimator.unfitting, podInfo.pod)
+				notFitting = append(notFitting, podInfo.pod)
+				continue
+			}
+			if len(newNodes) >= maxNodes {
+				notFitting = append(notFitting, podInfo.pod)
+				continue
+			}
+			newNodes = append(newNodes, nodeWithPod(nodeTemplate, podInfo.pod))
+		}
+	}
+	return newNodes, notFitting
+}
+
+// GetDebug returns debug information about the current state of BinpackingNodeEstimator
+func (estimator *BinpackingNodeEstimator) GetDebug() string {
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("Pods to pack: %d\n", len(estimator.pods)))
+	for _, pod := range estimator.unfitting {
+		buffer.WriteString(fmt.Sprintf("Pod %s/%s doesn't fit an empty node\n", pod.Namespace, pod.Name))
+	}
+	return buffer.String()
+}
+
+// GetCount returns number of pods included in the estimation.
+func (estimator *BinpackingNodeEstimator) GetCount() int {
+	return len(estimator.pods)
+}
+
+// nodeWithPod returns a copy of the given NodeInfo with the pod added.
+func nodeWithPod(nodeInfo *schedulercache.NodeInfo, pod *kube_api.Pod) *schedulercache.NodeInfo {
+	podsOnNode := nodeInfo.Pods()
+	podsOnNode = append(append([]*kube_api.Pod{}, podsOnNode...), pod)
+	newNodeInfo := schedulercache.NewNodeInfo(podsOnNode...)
+	newNodeInfo.SetNode(nodeInfo.Node())
+	return newNodeInfo
+}
+
+// calculatePodScore calculates the score for all pods and returns podInfo structure.
+// Score is defined as cpu_sum/node_capacity + mem_sum/node_capacity.
+// Pods that have bigger requirements should be processed first, thus have higher scores.
+func calculatePodScore(pods []*kube_api.Pod, nodeTemplate *kube_api.Node) []*podInfo {
+	podInfos := make([]*podInfo, 0, len(pods))
+
+	for _, pod := range pods {
+		cpuSum := int64(0)
+		memorySum := int64(0)
+
+		for _, container := range pod.Spec.Containers {
+			if request, ok := container.Resources.Requests[kube_api.ResourceCPU]; ok {
+				cpuSum += request.MilliValue()
+			}
+			if request, ok := container.Resources.Requests[kube_api.ResourceMemory]; ok {
+				memorySum += request.Value()
+			}
+		}
+		score := float64(0)
+		if cpuCapacity, ok := nodeTemplate.Status.Capacity[kube_api.ResourceCPU]; ok && cpuCapacity.MilliValue() > 0 {
+			score += float64(cpuSum) / float64(cpuCapacity.MilliValue())
+		}
+		if memCapacity, ok := nodeTemplate.Status.Capacity[kube_api.ResourceMemory]; ok && memCapacity.Value() > 0 {
+			score += float64(memorySum) / float64(memCapacity.Value())
+		}
+
+		podInfos = append(podInfos, &podInfo{
+			score: score,
+			pod:   pod,
+		})
+	}
+	return podInfos
+}
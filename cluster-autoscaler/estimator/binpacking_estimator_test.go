@@ -0,0 +1,85 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package estimator
+
+import (
+	"testing"
+
+	"k8s.io/contrib/cluster-autoscaler/simulator"
+	. "k8s.io/contrib/cluster-autoscaler/utils/test"
+
+	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinpackingEstimate(t *testing.T) {
+	estimator := NewBinpackingNodeEstimator(simulator.NewTestPredicateChecker())
+
+	for i := 0; i < 10; i++ {
+		estimator.Add(BuildTestPod("p", 350, 1000))
+	}
+	node := BuildTestNode("template", 1000, 10000)
+	nodeInfo := schedulercache.NewNodeInfo()
+	nodeInfo.SetNode(node)
+
+	estimate, report := estimator.Estimate(nodeInfo)
+	assert.Equal(t, 5, estimate)
+	assert.Contains(t, report, "bin-packing")
+	assert.Equal(t, 10, estimator.GetCount())
+}
+
+func TestBinpackingEstimateMixed(t *testing.T) {
+	estimator := NewBinpackingNodeEstimator(simulator.NewTestPredicateChecker())
+
+	// Two big pods need separate nodes, small pods fill the remaining space.
+	estimator.Add(BuildTestPod("small1", 200, 1000))
+	estimator.Add(BuildTestPod("big1", 700, 1000))
+	estimator.Add(BuildTestPod("small2", 200, 1000))
+	estimator.Add(BuildTestPod("big2", 700, 1000))
+	estimator.Add(BuildTestPod("too-big", 2000, 1000))
+
+	node := BuildTestNode("template", 1000, 10000)
+	nodeInfo := schedulercache.NewNodeInfo()
+	nodeInfo.SetNode(node)
+
+	estimate, _ := estimator.Estimate(nodeInfo)
+	assert.Equal(t, 2, estimate)
+	assert.Contains(t, estimator.GetDebug(), "too-big")
+}
+
+func TestBinpackingPodsNotFitting(t *testing.T) {
+	estimator := NewBinpackingNodeEstimator(simulator.NewTestPredicateChecker())
+
+	big1 := BuildTestPod("big1", 700, 1000)
+	big2 := BuildTestPod("big2", 600, 1000)
+	small := BuildTestPod("small", 200, 1000)
+	tooBig := BuildTestPod("too-big", 2000, 1000)
+	estimator.Add(small)
+	estimator.Add(big1)
+	estimator.Add(big2)
+	estimator.Add(tooBig)
+
+	node := BuildTestNode("template", 1000, 10000)
+	nodeInfo := schedulercache.NewNodeInfo()
+	nodeInfo.SetNode(node)
+
+	assert.Equal(t, []*kube_api.Pod{tooBig}, estimator.PodsNotFitting(nodeInfo, 2))
+	assert.Equal(t, []*kube_api.Pod{tooBig, big2}, estimator.PodsNotFitting(nodeInfo, 1))
+	assert.Equal(t, []*kube_api.Pod{tooBig, big1, big2, small}, estimator.PodsNotFitting(nodeInfo, 0))
+}
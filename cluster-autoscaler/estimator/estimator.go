@@ -25,6 +25,16 @@ import (
 	"k8s.io/kubernetes/pkg/api/resource"
 )
 
+const (
+	// BasicEstimatorName is the name of the estimator dividing summed pod requests by node capacity.
+	BasicEstimatorName = "basic"
+	// BinpackingEstimatorName is the name of the estimator simulating first-fit-decreasing bin-packing.
+	BinpackingEstimatorName = "binpacking"
+)
+
+// AvailableEstimators is a list of all estimator names.
+var AvailableEstimators = []string{BasicEstimatorName, BinpackingEstimatorName}
+
 // BasicNodeEstimator estimates the number of needed nodes to handle the given amount of pods.
 // It will never overestimate the number of nodes but is quite likekly to provide a number that
 // is too small.
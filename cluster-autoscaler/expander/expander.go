@@ -0,0 +1,84 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expander
+
+import (
+	"fmt"
+	"math/rand"
+
+	"k8s.io/contrib/cluster-autoscaler/cloudprovider"
+	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
+)
+
+const (
+	// FirstExpanderName selects the first node group, in the order they are configured.
+	FirstExpanderName = "first"
+	// RandomExpanderName selects a node group at random.
+	RandomExpanderName = "random"
+	// MostPodsExpanderName selects a node group that fits the most pods.
+	MostPodsExpanderName = "most-pods"
+	// LeastWasteExpanderName selects a node group that leaves the least fraction of
+	// CPU and memory unused on the new nodes.
+	LeastWasteExpanderName = "least-waste"
+	// PriorityExpanderName selects a node group with the highest configured priority.
+	PriorityExpanderName = "priority"
+)
+
+// AvailableExpanders is a list of all expander names.
+var AvailableExpanders = []string{FirstExpanderName, RandomExpanderName, MostPodsExpanderName, LeastWasteExpanderName, PriorityExpanderName}
+
+// Option describes an option to expand the cluster.
+type Option struct {
+	NodeGroup cloudprovider.NodeGroup
+	NodeCount int
+	Debug     string
+	Pods      []*kube_api.Pod
+}
+
+// Strategy describes an interface for selecting the best option when scaling up.
+type Strategy interface {
+	// BestOption picks the best cluster expansion option. NodeInfos are keyed by node group id
+	// and describe how a new node in the group would look like. Returns nil if there are no options.
+	BestOption(options []Option, nodeInfos map[string]*schedulercache.NodeInfo) *Option
+}
+
+// ExpanderStrategyFromString creates a Strategy according to its name. Priorities are only
+// used by the priority expander.
+func ExpanderStrategyFromString(name string, priorities []string) (Strategy, error) {
+	switch name {
+	case FirstExpanderName:
+		return NewFirstStrategy(), nil
+	case RandomExpanderName:
+		return NewRandomStrategy(), nil
+	case MostPodsExpanderName:
+		return NewMostPodsStrategy(), nil
+	case LeastWasteExpanderName:
+		return NewLeastWasteStrategy(), nil
+	case PriorityExpanderName:
+		return NewPriorityStrategy(priorities)
+	}
+	return nil, fmt.Errorf("expander %s not supported, expected one of %v", name, AvailableExpanders)
+}
+
+// pickRandom returns a random option from the given list or nil if the list is empty.
+func pickRandom(options []Option) *Option {
+	if len(options) == 0 {
+		return nil
+	}
+	return &options[rand.Intn(len(options))]
+}
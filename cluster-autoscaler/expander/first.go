@@ -0,0 +1,37 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expander
+
+import (
+	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
+)
+
+type firstStrategy struct{}
+
+// NewFirstStrategy returns a Strategy that selects the first option. Options follow the order in
+// which the cloud provider lists node groups, so this keeps the flag order of --nodes meaningful.
+func NewFirstStrategy() Strategy {
+	return &firstStrategy{}
+}
+
+// BestOption selects the first option.
+func (f *firstStrategy) BestOption(options []Option, nodeInfos map[string]*schedulercache.NodeInfo) *Option {
+	if len(options) == 0 {
+		return nil
+	}
+	return &options[0]
+}
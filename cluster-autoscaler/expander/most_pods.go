@@ -0,0 +1,49 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expander
+
+import (
+	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
+
+	"github.com/golang/glog"
+)
+
+type mostPodsStrategy struct{}
+
+// NewMostPodsStrategy returns a Strategy that selects the option which helps the most
+// pending pods. Ties are broken at random.
+func NewMostPodsStrategy() Strategy {
+	return &mostPodsStrategy{}
+}
+
+// BestOption selects the option that fits the most pods.
+func (m *mostPodsStrategy) BestOption(options []Option, nodeInfos map[string]*schedulercache.NodeInfo) *Option {
+	var best []Option
+	maxPods := -1
+	for _, option := range options {
+		count := len(option.Pods)
+		glog.V(2).Infof("Expansion option %s - nodes: %d, score (pods): %d", option.NodeGroup.Id(), option.NodeCount, count)
+		if count == maxPods {
+			best = append(best, option)
+		}
+		if count > maxPods {
+			maxPods = count
+			best = []Option{option}
+		}
+	}
+	return pickRandom(best)
+}
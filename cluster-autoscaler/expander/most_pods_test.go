@@ -0,0 +1,43 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expander
+
+import (
+	"testing"
+
+	testprovider "k8s.io/contrib/cluster-autoscaler/cloudprovider/test"
+	. "k8s.io/contrib/cluster-autoscaler/utils/test"
+
+	kube_api "k8s.io/kubernetes/pkg/api"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMostPods(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	ng1 := provider.AddNodeGroup("ng1", 1, 10, 1)
+	ng2 := provider.AddNodeGroup("ng2", 1, 10, 1)
+
+	p1 := BuildTestPod("p1", 100, 100)
+	p2 := BuildTestPod("p2", 100, 100)
+	options := []Option{
+		{NodeGroup: ng1, NodeCount: 1, Pods: []*kube_api.Pod{p1}},
+		{NodeGroup: ng2, NodeCount: 1, Pods: []*kube_api.Pod{p1, p2}},
+	}
+	best := NewMostPodsStrategy().BestOption(options, nil)
+	assert.Equal(t, "ng2", best.NodeGroup.Id())
+}
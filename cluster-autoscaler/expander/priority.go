@@ -0,0 +1,74 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expander
+
+import (
+	"fmt"
+	"regexp"
+
+	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
+
+	"github.com/golang/glog"
+)
+
+type priorityStrategy struct {
+	priorities []*regexp.Regexp
+}
+
+// NewPriorityStrategy returns a Strategy that selects the option whose node group id matches
+// the earliest regular expression in the priorities list. Node groups not matching any expression
+// have the lowest priority. Ties are broken at random.
+func NewPriorityStrategy(priorities []string) (Strategy, error) {
+	strategy := &priorityStrategy{
+		priorities: make([]*regexp.Regexp, 0, len(priorities)),
+	}
+	for _, priority := range priorities {
+		re, err := regexp.Compile(priority)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse expander priority %s: %v", priority, err)
+		}
+		strategy.priorities = append(strategy.priorities, re)
+	}
+	return strategy, nil
+}
+
+// BestOption selects the option with the highest priority.
+func (p *priorityStrategy) BestOption(options []Option, nodeInfos map[string]*schedulercache.NodeInfo) *Option {
+	var best []Option
+	maxScore := -1
+	for _, option := range options {
+		score := p.score(option.NodeGroup.Id())
+		glog.V(2).Infof("Expansion option %s - nodes: %d, score (priority): %d", option.NodeGroup.Id(), option.NodeCount, score)
+		if score == maxScore {
+			best = append(best, option)
+		}
+		if score > maxScore {
+			maxScore = score
+			best = []Option{option}
+		}
+	}
+	return pickRandom(best)
+}
+
+func (p *priorityStrategy) score(id string) int {
+	for i, re := range p.priorities {
+		if re.MatchString(id) {
+			return len(p.priorities) - i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expander
+
+import (
+	"testing"
+
+	testprovider "k8s.io/contrib/cluster-autoscaler/cloudprovider/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriority(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	general := provider.AddNodeGroup("general-pool", 1, 10, 1)
+	highmem := provider.AddNodeGroup("highmem-pool", 1, 10, 1)
+	other := provider.AddNodeGroup("other", 1, 10, 1)
+	options := []Option{{NodeGroup: general}, {NodeGroup: highmem}, {NodeGroup: other}}
+
+	strategy, err := NewPriorityStrategy([]string{"^highmem-.*", "^general-.*"})
+	assert.NoError(t, err)
+	assert.Equal(t, "highmem-pool", strategy.BestOption(options, nil).NodeGroup.Id())
+	assert.Equal(t, "general-pool", strategy.BestOption(options[:1], nil).NodeGroup.Id())
+
+	_, err = NewPriorityStrategy([]string{"("})
+	assert.Error(t, err)
+
+	_, err = ExpanderStrategyFromString("unknown", nil)
+	assert.Error(t, err)
+}
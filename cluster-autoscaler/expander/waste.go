@@ -0,0 +1,83 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expander
+
+import (
+	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/resource"
+	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
+
+	"github.com/golang/glog"
+)
+
+type leastWasteStrategy struct{}
+
+// NewLeastWasteStrategy returns a Strategy that selects the option which leaves the smallest
+// fraction of CPU and memory unrequested on the new nodes. Ties are broken at random.
+func NewLeastWasteStrategy() Strategy {
+	return &leastWasteStrategy{}
+}
+
+// BestOption selects the option with the lowest waste score.
+func (l *leastWasteStrategy) BestOption(options []Option, nodeInfos map[string]*schedulercache.NodeInfo) *Option {
+	var best []Option
+	leastWaste := float64(-1)
+	for _, option := range options {
+		nodeInfo, found := nodeInfos[option.NodeGroup.Id()]
+		if !found || nodeInfo.Node() == nil || option.NodeCount <= 0 {
+			glog.Warningf("No node info for %s, skipping in least-waste expander", option.NodeGroup.Id())
+			continue
+		}
+		cpuWaste := wastedFraction(option, nodeInfo.Node(), kube_api.ResourceCPU)
+		memWaste := wastedFraction(option, nodeInfo.Node(), kube_api.ResourceMemory)
+		waste := (cpuWaste + memWaste) / 2
+		glog.V(2).Infof("Expansion option %s - nodes: %d, score (waste): %f (cpu: %f, mem: %f)",
+			option.NodeGroup.Id(), option.NodeCount, waste, cpuWaste, memWaste)
+
+		if waste == leastWaste {
+			best = append(best, option)
+		}
+		if leastWaste < 0 || waste < leastWaste {
+			leastWaste = waste
+			best = []Option{option}
+		}
+	}
+	return pickRandom(best)
+}
+
+// wastedFraction returns the fraction of the given resource that would stay unrequested
+// on the option's new nodes once all its pods are placed there.
+func wastedFraction(option Option, node *kube_api.Node, resourceName kube_api.ResourceName) float64 {
+	capacity, found := node.Status.Capacity[resourceName]
+	if !found || capacity.MilliValue() == 0 {
+		return 0
+	}
+	requested := resource.MustParse("0")
+	for _, pod := range option.Pods {
+		for _, container := range pod.Spec.Containers {
+			if request, found := container.Resources.Requests[resourceName]; found {
+				requested.Add(request)
+			}
+		}
+	}
+	available := float64(capacity.MilliValue()) * float64(option.NodeCount)
+	waste := (available - float64(requested.MilliValue())) / available
+	if waste < 0 {
+		return 0
+	}
+	return waste
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expander
+
+import (
+	"testing"
+
+	testprovider "k8s.io/contrib/cluster-autoscaler/cloudprovider/test"
+	. "k8s.io/contrib/cluster-autoscaler/utils/test"
+
+	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeastWaste(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	small := provider.AddNodeGroup("small", 1, 10, 1)
+	big := provider.AddNodeGroup("big", 1, 10, 1)
+
+	smallInfo := schedulercache.NewNodeInfo()
+	smallInfo.SetNode(BuildTestNode("s", 1000, 1000))
+	bigInfo := schedulercache.NewNodeInfo()
+	bigInfo.SetNode(BuildTestNode("b", 4000, 4000))
+	nodeInfos := map[string]*schedulercache.NodeInfo{"small": smallInfo, "big": bigInfo}
+
+	pods := []*kube_api.Pod{BuildTestPod("p1", 900, 900)}
+	options := []Option{
+		{NodeGroup: small, NodeCount: 1, Pods: pods},
+		{NodeGroup: big, NodeCount: 1, Pods: pods},
+	}
+	best := NewLeastWasteStrategy().BestOption(options, nodeInfos)
+	assert.Equal(t, "small", best.NodeGroup.Id())
+
+	assert.Nil(t, NewLeastWasteStrategy().BestOption([]Option{}, nodeInfos))
+}
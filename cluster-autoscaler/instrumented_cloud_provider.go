@@ -0,0 +1,122 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"k8s.io/contrib/cluster-autoscaler/cloudprovider"
+	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
+)
+
+// instrumentedCloudProvider counts the failed calls of the wrapped cloud provider.
+type instrumentedCloudProvider struct {
+	cloudProvider cloudprovider.CloudProvider
+}
+
+// NewInstrumentedCloudProvider wraps the cloud provider so that its failed calls are exported
+// as metrics.
+func NewInstrumentedCloudProvider(cloudProvider cloudprovider.CloudProvider) cloudprovider.CloudProvider {
+	return &instrumentedCloudProvider{cloudProvider: cloudProvider}
+}
+
+// NodeGroups returns all node groups configured for this cloud provider.
+func (icp *instrumentedCloudProvider) NodeGroups() []cloudprovider.NodeGroup {
+	nodeGroups := icp.cloudProvider.NodeGroups()
+	result := make([]cloudprovider.NodeGroup, 0, len(nodeGroups))
+	for _, nodeGroup := range nodeGroups {
+		result = append(result, &instrumentedNodeGroup{nodeGroup: nodeGroup})
+	}
+	return result
+}
+
+// NodeGroupForNode returns the node group for the given node.
+func (icp *instrumentedCloudProvider) NodeGroupForNode(node *kube_api.Node) (cloudprovider.NodeGroup, error) {
+	nodeGroup, err := icp.cloudProvider.NodeGroupForNode(node)
+	if err != nil {
+		failedCloudProviderCallsCount.WithLabelValues("node_group_for_node").Inc()
+		return nil, err
+	}
+	return &instrumentedNodeGroup{nodeGroup: nodeGroup}, nil
+}
+
+// instrumentedNodeGroup counts the failed calls of the wrapped node group.
+type instrumentedNodeGroup struct {
+	nodeGroup cloudprovider.NodeGroup
+}
+
+// MaxSize returns maximum size of the node group.
+func (ing *instrumentedNodeGroup) MaxSize() int {
+	return ing.nodeGroup.MaxSize()
+}
+
+// MinSize returns minimum size of the node group.
+func (ing *instrumentedNodeGroup) MinSize() int {
+	return ing.nodeGroup.MinSize()
+}
+
+// TargetSize returns the current target size of the node group.
+func (ing *instrumentedNodeGroup) TargetSize() (int, error) {
+	size, err := ing.nodeGroup.TargetSize()
+	countFailedCall("target_size", err)
+	return size, err
+}
+
+// SetTargetSize sets the target size of the node group.
+func (ing *instrumentedNodeGroup) SetTargetSize(size int) error {
+	err := ing.nodeGroup.SetTargetSize(size)
+	countFailedCall("set_target_size", err)
+	return err
+}
+
+// DeleteNodes deletes the given nodes from the node group.
+func (ing *instrumentedNodeGroup) DeleteNodes(nodes []*kube_api.Node) error {
+	err := ing.nodeGroup.DeleteNodes(nodes)
+	countFailedCall("delete_nodes", err)
+	return err
+}
+
+// Nodes returns the ids of all instances of the node group.
+func (ing *instrumentedNodeGroup) Nodes() ([]string, error) {
+	nodes, err := ing.nodeGroup.Nodes()
+	countFailedCall("nodes", err)
+	return nodes, err
+}
+
+// Id returns an unique identifier of the node group.
+func (ing *instrumentedNodeGroup) Id() string {
+	return ing.nodeGroup.Id()
+}
+
+// Debug returns a string containing all information regarding this node group.
+func (ing *instrumentedNodeGroup) Debug() string {
+	return ing.nodeGroup.Debug()
+}
+
+// TemplateNodeInfo returns a node template for this node group.
+func (ing *instrumentedNodeGroup) TemplateNodeInfo() (*schedulercache.NodeInfo, error) {
+	nodeInfo, err := ing.nodeGroup.TemplateNodeInfo()
+	if err != cloudprovider.ErrNotImplemented {
+		countFailedCall("template_node_info", err)
+	}
+	return nodeInfo, err
+}
+
+func countFailedCall(operation string, err error) {
+	if err != nil {
+		failedCloudProviderCallsCount.WithLabelValues(operation).Inc()
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	testprovider "k8s.io/contrib/cluster-autoscaler/cloudprovider/test"
+	. "k8s.io/contrib/cluster-autoscaler/utils/test"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func failedCalls(t *testing.T, operation string) float64 {
+	metric := &dto.Metric{}
+	assert.NoError(t, failedCloudProviderCallsCount.WithLabelValues(operation).Write(metric))
+	return metric.GetCounter().GetValue()
+}
+
+func TestInstrumentedCloudProvider(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	provider := testprovider.NewTestCloudProvider(func(id string, size int) error {
+		return fmt.Errorf("quota exceeded")
+	}, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNode("ng1", n1)
+	instrumented := NewInstrumentedCloudProvider(provider)
+
+	setTargetSizeFailures := failedCalls(t, "set_target_size")
+	nodeGroup, err := instrumented.NodeGroupForNode(n1)
+	assert.NoError(t, err)
+	assert.Equal(t, "ng1", nodeGroup.Id())
+	assert.Error(t, nodeGroup.SetTargetSize(2))
+	assert.Equal(t, setTargetSizeFailures+1, failedCalls(t, "set_target_size"))
+
+	// Node groups without a template are not failures.
+	templateFailures := failedCalls(t, "template_node_info")
+	_, err = instrumented.NodeGroups()[0].TemplateNodeInfo()
+	assert.Error(t, err)
+	assert.Equal(t, templateFailures, failedCalls(t, "template_node_info"))
+
+	nodeGroupForNodeFailures := failedCalls(t, "node_group_for_node")
+	_, err = instrumented.NodeGroupForNode(BuildTestNode("n2", 1000, 1000))
+	assert.Error(t, err)
+	assert.Equal(t, nodeGroupForNodeFailures+1, failedCalls(t, "node_group_for_node"))
+}
@@ -39,6 +39,14 @@ var (
 		}, []string{"main"},
 	)
 
+	leader = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "cluster_autoscaler",
+			Name:      "leader",
+			Help:      "1 if this replica is the elected leader or leader election is disabled, 0 otherwise.",
+		},
+	)
+
 	duration = prometheus.NewSummaryVec(
 		prometheus.SummaryOpts{
 			Namespace: "cluster_autoscaler",
@@ -46,14 +54,121 @@ var (
 			Help:      "Time spent in main loop fragments in microseconds.",
 		}, []string{"main"},
 	)
+
+	scaleUpCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "cluster_autoscaler",
+			Name:      "scale_ups_total",
+			Help:      "Number of times node groups were scaled up.",
+		}, []string{"node_group"},
+	)
+
+	scaledUpNodesCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "cluster_autoscaler",
+			Name:      "scaled_up_nodes_total",
+			Help:      "Number of nodes requested in scale ups.",
+		}, []string{"node_group"},
+	)
+
+	scaledDownNodesCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "cluster_autoscaler",
+			Name:      "scaled_down_nodes_total",
+			Help:      "Number of nodes removed in scale downs.",
+		}, []string{"node_group"},
+	)
+
+	scaleDownSkippedCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "cluster_autoscaler",
+			Name:      "scale_down_skipped_total",
+			Help:      "Number of scale down trials that didn't remove any node, by reason.",
+		}, []string{"reason"},
+	)
+
+	failedCloudProviderCallsCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "cluster_autoscaler",
+			Name:      "failed_cloud_provider_calls_total",
+			Help:      "Number of failed cloud provider calls, by operation.",
+		}, []string{"operation"},
+	)
+
+	unschedulablePodsCount = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "cluster_autoscaler",
+			Name:      "unschedulable_pods_count",
+			Help:      "Number of unschedulable pods that CA tries to help in the last loop.",
+		},
+	)
+
+	unneededNodesCount = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "cluster_autoscaler",
+			Name:      "unneeded_nodes_count",
+			Help:      "Number of nodes that are currently candidates for scale down.",
+		},
+	)
+
+	nodeGroupSize = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "cluster_autoscaler",
+			Name:      "node_group_size",
+			Help:      "Number of registered (current) and requested (target) nodes of each node group.",
+		}, []string{"node_group", "type"},
+	)
 )
 
+// exportedNodeGroups are the node groups whose sizes are exported.
+var exportedNodeGroups = make(map[string]bool)
+
 func init() {
 	prometheus.MustRegister(duration)
 	prometheus.MustRegister(lastDuration)
 	prometheus.MustRegister(lastTimestamp)
+	prometheus.MustRegister(leader)
+	prometheus.MustRegister(scaleUpCount)
+	prometheus.MustRegister(scaledUpNodesCount)
+	prometheus.MustRegister(scaledDownNodesCount)
+	prometheus.MustRegister(scaleDownSkippedCount)
+	prometheus.MustRegister(failedCloudProviderCallsCount)
+	prometheus.MustRegister(unschedulablePodsCount)
+	prometheus.MustRegister(unneededNodesCount)
+	prometheus.MustRegister(nodeGroupSize)
 }
 
 func durationToMicro(start time.Time) float64 {
 	return float64(time.Now().Sub(start).Nanoseconds() / 1000)
 }
+
+// updateNodeGroupSizeMetrics exports the current and target sizes of the node groups in the status.
+// The sizes of node groups that are no longer in the status, e.g. removed by a config reload, are
+// not exported anymore.
+func updateNodeGroupSizeMetrics(status *ClusterStatus) {
+	exported := make(map[string]bool)
+	for _, groupStatus := range status.NodeGroups {
+		nodeGroupSize.WithLabelValues(groupStatus.Id, "current").Set(float64(groupStatus.CurrentSize))
+		nodeGroupSize.WithLabelValues(groupStatus.Id, "target").Set(float64(groupStatus.TargetSize))
+		exported[groupStatus.Id] = true
+	}
+	for id := range exportedNodeGroups {
+		if !exported[id] {
+			nodeGroupSize.DeleteLabelValues(id, "current")
+			nodeGroupSize.DeleteLabelValues(id, "target")
+		}
+	}
+	exportedNodeGroups = exported
+}
+
+// updateScaleDownSkipped counts a scale down trial that didn't remove any node.
+func updateScaleDownSkipped(result ScaleDownResult) {
+	switch result {
+	case ScaleDownError:
+		scaleDownSkippedCount.WithLabelValues("error").Inc()
+	case ScaleDownNoUnneeded:
+		scaleDownSkippedCount.WithLabelValues("no_unneeded").Inc()
+	case ScaleDownNoNodeDeleted:
+		scaleDownSkippedCount.WithLabelValues("no_node_deleted").Inc()
+	}
+}
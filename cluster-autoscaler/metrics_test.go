@@ -0,0 +1,39 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateNodeGroupSizeMetrics(t *testing.T) {
+	updateNodeGroupSizeMetrics(&ClusterStatus{NodeGroups: []NodeGroupStatus{
+		{Id: "ng1", CurrentSize: 1, TargetSize: 2},
+		{Id: "ng2", CurrentSize: 3, TargetSize: 3},
+	}})
+	// ng2 was removed.
+	updateNodeGroupSizeMetrics(&ClusterStatus{NodeGroups: []NodeGroupStatus{
+		{Id: "ng1", CurrentSize: 2, TargetSize: 2},
+	}})
+
+	assert.False(t, nodeGroupSize.DeleteLabelValues("ng2", "current"))
+	assert.False(t, nodeGroupSize.DeleteLabelValues("ng2", "target"))
+	assert.True(t, nodeGroupSize.DeleteLabelValues("ng1", "current"))
+	assert.True(t, nodeGroupSize.DeleteLabelValues("ng1", "target"))
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"time"
+
+	"k8s.io/contrib/cluster-autoscaler/cloudprovider"
+	kube_api "k8s.io/kubernetes/pkg/api"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// MaxUnreadyNodesDeletedPerGroup is the maximum number of unready nodes deleted from a node
+	// group in one loop.
+	MaxUnreadyNodesDeletedPerGroup = 1
+)
+
+// UnhealthyNodeCleaner finds instances of node groups that never registered in Kubernetes and
+// nodes that have been not ready for too long, and deletes them through the cloud provider.
+// Unready nodes are deleted a few at a time and not at all if a large part of the cluster or of
+// the node group is unready, as that is more likely a network or control plane problem than
+// broken machines.
+type UnhealthyNodeCleaner struct {
+	maxNodeProvisionTime time.Duration
+	maxNodeUnreadyTime   time.Duration
+	maxUnreadyBulkDelete int
+	maxUnreadyPercentage float64
+	// Time since which each instance has been seen without a registered node.
+	unregisteredSince map[string]time.Time
+}
+
+// NewUnhealthyNodeCleaner builds new UnhealthyNodeCleaner. Instances are deleted if they don't
+// register within maxNodeProvisionTime, nodes if they are not ready for maxNodeUnreadyTime.
+// At most maxUnreadyBulkDelete unready nodes are deleted in one loop, and none from the node groups
+// with more than maxUnreadyPercentage percent of nodes unready.
+func NewUnhealthyNodeCleaner(maxNodeProvisionTime, maxNodeUnreadyTime time.Duration, maxUnreadyBulkDelete int,
+	maxUnreadyPercentage float64) *UnhealthyNodeCleaner {
+	return &UnhealthyNodeCleaner{
+		maxNodeProvisionTime: maxNodeProvisionTime,
+		maxNodeUnreadyTime:   maxNodeUnreadyTime,
+		maxUnreadyBulkDelete: maxUnreadyBulkDelete,
+		maxUnreadyPercentage: maxUnreadyPercentage,
+		unregisteredSince:    make(map[string]time.Time),
+	}
+}
+
+// SetTimeouts changes the times after which unregistered instances and unready nodes are deleted.
+func (cleaner *UnhealthyNodeCleaner) SetTimeouts(maxNodeProvisionTime, maxNodeUnreadyTime time.Duration) {
+	cleaner.maxNodeProvisionTime = maxNodeProvisionTime
+	cleaner.maxNodeUnreadyTime = maxNodeUnreadyTime
+}
+
+// SetUnreadyLimits changes how many unready nodes are deleted in one loop and the share of
+// unready nodes above which none are deleted.
+func (cleaner *UnhealthyNodeCleaner) SetUnreadyLimits(maxUnreadyBulkDelete int, maxUnreadyPercentage float64) {
+	cleaner.maxUnreadyBulkDelete = maxUnreadyBulkDelete
+	cleaner.maxUnreadyPercentage = maxUnreadyPercentage
+}
+
+// CleanUp deletes the unregistered instances and long unready nodes of all node groups. Node groups
+// that end up below their min size are resized back to it, so that the deleted instances are replaced.
+func (cleaner *UnhealthyNodeCleaner) CleanUp(context *AutoscalingContext, allNodes []*kube_api.Node, now time.Time) {
+	registered := make(map[string]*kube_api.Node)
+	clusterUnready := 0
+	for _, node := range allNodes {
+		registered[node.Spec.ProviderID] = node
+		if _, unready := getNodeUnreadySince(node); unready {
+			clusterUnready++
+		}
+	}
+	unreadyToDelete := cleaner.maxUnreadyBulkDelete
+	if cleaner.tooManyUnready(clusterUnready, len(allNodes)) {
+		glog.Warningf("%d of %d nodes are not ready, not deleting unready nodes", clusterUnready, len(allNodes))
+		unreadyToDelete = 0
+	}
+
+	seen := make(map[string]bool)
+	for _, nodeGroup := range context.CloudProvider.NodeGroups() {
+		instances, err := nodeGroup.Nodes()
+		if err != nil {
+			glog.Errorf("Failed to list instances of %s: %v", nodeGroup.Id(), err)
+			continue
+		}
+		targetSize, err := nodeGroup.TargetSize()
+		if err != nil {
+			glog.Errorf("Failed to get size of %s: %v", nodeGroup.Id(), err)
+			continue
+		}
+		// Instances above the target size are already being removed by the cloud provider.
+		unregisteredToDelete := targetSize
+		for _, instance := range instances {
+			if _, found := registered[instance]; found {
+				unregisteredToDelete--
+			}
+		}
+
+		groupUnready, groupRegistered := 0, 0
+		for _, instance := range instances {
+			if node, found := registered[instance]; found {
+				groupRegistered++
+				if _, unready := getNodeUnreadySince(node); unready {
+					groupUnready++
+				}
+			}
+		}
+		groupUnreadyToDelete := MaxUnreadyNodesDeletedPerGroup
+		if cleaner.tooManyUnready(groupUnready, groupRegistered) {
+			glog.Warningf("%d of %d nodes of %s are not ready, not deleting unready nodes", groupUnready,
+				groupRegistered, nodeGroup.Id())
+			groupUnreadyToDelete = 0
+		}
+
+		toDelete := make([]*kube_api.Node, 0)
+		for _, instance := range instances {
+			seen[instance] = true
+			node, found := registered[instance]
+			if !found {
+				since, found := cleaner.unregisteredSince[instance]
+				if !found {
+					cleaner.unregisteredSince[instance] = now
+					continue
+				}
+				if now.Sub(since) >= cleaner.maxNodeProvisionTime && unregisteredToDelete > 0 {
+					glog.Warningf("Instance %s of %s didn't register for %v, deleting it", instance, nodeGroup.Id(), now.Sub(since))
+					toDelete = append(toDelete, &kube_api.Node{
+						ObjectMeta: kube_api.ObjectMeta{Name: instance},
+						Spec:       kube_api.NodeSpec{ProviderID: instance},
+					})
+					unregisteredToDelete--
+				}
+				continue
+			}
+			delete(cleaner.unregisteredSince, instance)
+			if unreadySince, unready := getNodeUnreadySince(node); unready && now.Sub(unreadySince) >= cleaner.maxNodeUnreadyTime {
+				if unreadyToDelete <= 0 || groupUnreadyToDelete <= 0 {
+					glog.V(1).Infof("Node %s of %s is not ready for %v, leaving it for now", node.Name, nodeGroup.Id(),
+						now.Sub(unreadySince))
+					continue
+				}
+				unreadyToDelete--
+				groupUnreadyToDelete--
+				glog.Warningf("Node %s of %s is not ready for %v, deleting it", node.Name, nodeGroup.Id(), now.Sub(unreadySince))
+				context.Recorder.Eventf(node, kube_api.EventTypeWarning, "DeletingUnreadyNode",
+					"node is not ready for %v and is deleted by cluster autoscaler", now.Sub(unreadySince))
+				toDelete = append(toDelete, node)
+			}
+		}
+		if len(toDelete) > 0 {
+			deleteUnhealthyNodes(nodeGroup, toDelete)
+		}
+	}
+
+	for instance := range cleaner.unregisteredSince {
+		if !seen[instance] {
+			delete(cleaner.unregisteredSince, instance)
+		}
+	}
+}
+
+// tooManyUnready returns true if more than one of the nodes and more than maxUnreadyPercentage percent
+// of them are not ready. A single unready node is always deleted, even from a small node group.
+func (cleaner *UnhealthyNodeCleaner) tooManyUnready(unready, total int) bool {
+	return unready > 1 && float64(unready)*100 > cleaner.maxUnreadyPercentage*float64(total)
+}
+
+// deleteUnhealthyNodes deletes the nodes and resizes the node group back to its min size if needed.
+func deleteUnhealthyNodes(nodeGroup cloudprovider.NodeGroup, nodes []*kube_api.Node) {
+	if err := nodeGroup.DeleteNodes(nodes); err != nil {
+		glog.Errorf("Failed to delete unhealthy nodes of %s: %v", nodeGroup.Id(), err)
+		return
+	}
+	size, err := nodeGroup.TargetSize()
+	if err != nil {
+		glog.Errorf("Failed to get size of %s: %v", nodeGroup.Id(), err)
+		return
+	}
+	if size < nodeGroup.MinSize() {
+		glog.V(1).Infof("Resizing %s from %d back to min size %d", nodeGroup.Id(), size, nodeGroup.MinSize())
+		if err := nodeGroup.SetTargetSize(nodeGroup.MinSize()); err != nil {
+			glog.Errorf("Failed to resize %s: %v", nodeGroup.Id(), err)
+		}
+	}
+}
+
+// getNodeUnreadySince returns the time of the last ready condition transition and whether the node
+// is not ready. Nodes without the ready condition are not ready since their creation.
+func getNodeUnreadySince(node *kube_api.Node) (time.Time, bool) {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == kube_api.NodeReady {
+			return condition.LastTransitionTime.Time, condition.Status != kube_api.ConditionTrue
+		}
+	}
+	return node.CreationTimestamp.Time, true
+}
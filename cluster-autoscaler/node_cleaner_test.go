@@ -0,0 +1,159 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	testprovider "k8s.io/contrib/cluster-autoscaler/cloudprovider/test"
+	. "k8s.io/contrib/cluster-autoscaler/utils/test"
+
+	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	kube_record "k8s.io/kubernetes/pkg/client/record"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildReadyCondition(status kube_api.ConditionStatus, since time.Time) []kube_api.NodeCondition {
+	return []kube_api.NodeCondition{
+		{
+			Type:               kube_api.NodeReady,
+			Status:             status,
+			LastTransitionTime: unversioned.NewTime(since),
+		},
+	}
+}
+
+func TestUnhealthyNodeCleanerCleanUp(t *testing.T) {
+	now := time.Now()
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n1.Status.Conditions = buildReadyCondition(kube_api.ConditionTrue, now.Add(-time.Hour))
+	n2 := BuildTestNode("n2", 1000, 1000)
+	n2.Status.Conditions = buildReadyCondition(kube_api.ConditionFalse, now.Add(-30*time.Minute))
+	n3 := BuildTestNode("n3", 1000, 1000)
+	n4 := BuildTestNode("n4", 1000, 1000)
+	n4.Status.Conditions = buildReadyCondition(kube_api.ConditionUnknown, now.Add(-time.Hour))
+	n5 := BuildTestNode("n5", 1000, 1000)
+	n5.Status.Conditions = buildReadyCondition(kube_api.ConditionFalse, now.Add(-time.Minute))
+
+	deleted := make([]string, 0)
+	provider := testprovider.NewTestCloudProvider(nil, func(id string, node string) error {
+		deleted = append(deleted, node)
+		return nil
+	})
+	provider.AddNodeGroup("ng1", 1, 10, 4)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng1", n2)
+	// n3 never registered.
+	provider.AddNode("ng1", n3)
+	provider.AddNode("ng1", n5)
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	provider.AddNode("ng2", n4)
+
+	recorder := kube_record.NewFakeRecorder(10)
+	context := &AutoscalingContext{
+		CloudProvider: provider,
+		Recorder:      recorder,
+	}
+	allNodes := []*kube_api.Node{n1, n2, n4, n5}
+	cleaner := NewUnhealthyNodeCleaner(15*time.Minute, 20*time.Minute, 10, 100)
+
+	cleaner.CleanUp(context, allNodes, now)
+	assert.Equal(t, []string{"n2", "n4"}, deleted)
+	assert.Contains(t, <-recorder.Events, "DeletingUnreadyNode")
+	size, _ := provider.GetNodeGroup("ng1").TargetSize()
+	assert.Equal(t, 3, size)
+	// ng2 is resized back to its min size.
+	size, _ = provider.GetNodeGroup("ng2").TargetSize()
+	assert.Equal(t, 1, size)
+
+	deleted = make([]string, 0)
+	cleaner.CleanUp(context, []*kube_api.Node{n1, n5}, now.Add(10*time.Minute))
+	assert.Empty(t, deleted)
+
+	cleaner.CleanUp(context, []*kube_api.Node{n1, n5}, now.Add(16*time.Minute))
+	assert.Equal(t, []string{"n3"}, deleted)
+	size, _ = provider.GetNodeGroup("ng1").TargetSize()
+	assert.Equal(t, 2, size)
+}
+
+func TestUnhealthyNodeCleanerUnreadyLimits(t *testing.T) {
+	now := time.Now()
+	// Builds ng1 with nodes a1-a6, ng2 with b1-b4 and ng3 with c1-c2. The given nodes are not ready
+	// for an hour, the other ones are ready.
+	buildCluster := func(unready ...string) (*AutoscalingContext, []*kube_api.Node, *[]string) {
+		deleted := make([]string, 0)
+		provider := testprovider.NewTestCloudProvider(nil, func(id string, node string) error {
+			deleted = append(deleted, node)
+			return nil
+		})
+		isUnready := make(map[string]bool)
+		for _, name := range unready {
+			isUnready[name] = true
+		}
+		allNodes := make([]*kube_api.Node, 0)
+		for id, names := range map[string][]string{
+			"ng1": {"a1", "a2", "a3", "a4", "a5", "a6"},
+			"ng2": {"b1", "b2", "b3", "b4"},
+			"ng3": {"c1", "c2"},
+		} {
+			provider.AddNodeGroup(id, 0, 10, len(names))
+			for _, name := range names {
+				node := BuildTestNode(name, 1000, 1000)
+				if isUnready[name] {
+					node.Status.Conditions = buildReadyCondition(kube_api.ConditionFalse, now.Add(-time.Hour))
+				} else {
+					node.Status.Conditions = buildReadyCondition(kube_api.ConditionTrue, now.Add(-time.Hour))
+				}
+				provider.AddNode(id, node)
+				allNodes = append(allNodes, node)
+			}
+		}
+		context := &AutoscalingContext{
+			CloudProvider: provider,
+			Recorder:      kube_record.NewFakeRecorder(10),
+		}
+		return context, allNodes, &deleted
+	}
+
+	// At most one node is deleted from a node group.
+	context, allNodes, deleted := buildCluster("a1", "a2")
+	NewUnhealthyNodeCleaner(15*time.Minute, 20*time.Minute, 10, 50).CleanUp(context, allNodes, now)
+	assert.Equal(t, []string{"a1"}, *deleted)
+
+	// At most maxUnreadyBulkDelete nodes are deleted in one loop.
+	context, allNodes, deleted = buildCluster("a1", "b1")
+	NewUnhealthyNodeCleaner(15*time.Minute, 20*time.Minute, 1, 50).CleanUp(context, allNodes, now)
+	assert.Equal(t, 1, len(*deleted))
+
+	// A single unready node is deleted even if it is a large part of its node group.
+	context, allNodes, deleted = buildCluster("c1")
+	NewUnhealthyNodeCleaner(15*time.Minute, 20*time.Minute, 10, 10).CleanUp(context, allNodes, now)
+	assert.Equal(t, []string{"c1"}, *deleted)
+
+	// No node is deleted from a node group with too many unready nodes.
+	context, allNodes, deleted = buildCluster("a1", "c1", "c2")
+	NewUnhealthyNodeCleaner(15*time.Minute, 20*time.Minute, 10, 50).CleanUp(context, allNodes, now)
+	assert.Equal(t, []string{"a1"}, *deleted)
+
+	// No node is deleted if too many nodes of the cluster are unready.
+	context, allNodes, deleted = buildCluster("a1", "a2", "b1", "b2", "c1")
+	NewUnhealthyNodeCleaner(15*time.Minute, 20*time.Minute, 10, 40).CleanUp(context, allNodes, now)
+	assert.Empty(t, *deleted)
+}
@@ -17,14 +17,17 @@ limitations under the License.
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
-	"k8s.io/contrib/cluster-autoscaler/config"
 	"k8s.io/contrib/cluster-autoscaler/simulator"
-	"k8s.io/contrib/cluster-autoscaler/utils/gce"
 	kube_api "k8s.io/kubernetes/pkg/api"
+	kube_errors "k8s.io/kubernetes/pkg/api/errors"
+	"k8s.io/kubernetes/pkg/apis/policy"
 	kube_client "k8s.io/kubernetes/pkg/client/unversioned"
+	"k8s.io/kubernetes/pkg/types"
+	"k8s.io/kubernetes/pkg/util/wait"
 	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
 
 	"github.com/golang/glog"
@@ -44,19 +47,43 @@ const (
 	ScaleDownNodeDeleted ScaleDownResult = iota
 )
 
+const (
+	// CordonedByAutoscalerAnnotation is put on nodes that were marked unschedulable by cluster
+	// autoscaler before being drained.
+	CordonedByAutoscalerAnnotation = "cluster-autoscaler.kubernetes.io/cordoned"
+	// ScaleDownDisabledAnnotation set to "true" on a node prevents cluster autoscaler from removing it.
+	ScaleDownDisabledAnnotation = "cluster-autoscaler.kubernetes.io/scale-down-disabled"
+
+	podEvictionPollInterval = 5 * time.Second
+	maxNodeUpdateRetries    = 3
+)
+
 // FindUnneededNodes calculates which nodes are not needed, i.e. all pods can be scheduled somewhere else,
-// and updates unneededNodes map accordingly.
-func FindUnneededNodes(nodes []*kube_api.Node,
+// and updates unneededNodes map accordingly. Nodes whose removal would violate a PodDisruptionBudget
+// are not considered unneeded. Nodes annotated with ScaleDownDisabledAnnotation and nodes running pods
+// that are not safe to evict are skipped. A node event with the reason is recorded when a node starts
+// being skipped or the reason changes.
+func FindUnneededNodes(
+	context *AutoscalingContext,
+	nodes []*kube_api.Node,
 	unneededNodes map[string]time.Time,
-	utilizationThreshold float64,
 	pods []*kube_api.Pod,
-	predicateChecker *simulator.PredicateChecker) map[string]time.Time {
+	podDisruptionBudgets []*policy.PodDisruptionBudget) map[string]time.Time {
 
 	currentlyUnneededNodes := make([]*kube_api.Node, 0)
 	nodeNameToNodeInfo := schedulercache.CreateNodeNameToInfoMap(pods)
+	scaleDownDisabledNodes := make(map[string]string)
+	defer func() { context.ScaleDownDisabledNodes = scaleDownDisabledNodes }()
 
 	// Phase1 - look at the nodes utilization.
 	for _, node := range nodes {
+		if node.Annotations[ScaleDownDisabledAnnotation] == "true" {
+			glog.V(3).Infof("Skipping %s - scale down disabled by annotation", node.Name)
+			recordScaleDownDisabled(context, node, scaleDownDisabledNodes,
+				fmt.Sprintf("node is annotated with %s=true", ScaleDownDisabledAnnotation))
+			continue
+		}
+
 		nodeInfo, found := nodeNameToNodeInfo[node.Name]
 		if !found {
 			glog.Errorf("Node info for %s not found", node.Name)
@@ -69,16 +96,31 @@ func FindUnneededNodes(nodes []*kube_api.Node,
 		}
 		glog.V(4).Infof("Node %s - utilization %f", node.Name, utilization)
 
-		if utilization >= utilizationThreshold {
+		threshold := context.ScaleDownUtilizationThreshold
+		if len(context.NodeGroupScaleDownOptions) > 0 {
+			if nodeGroup, err := context.CloudProvider.NodeGroupForNode(node); err == nil {
+				threshold = context.GetScaleDownUtilizationThreshold(nodeGroup.Id())
+			} else {
+				glog.Warningf("Failed to get node group for %s, using default utilization threshold: %v", node.Name, err)
+			}
+		}
+		if utilization >= threshold {
 			glog.V(4).Infof("Node %s is not suitable for removal - utilization to big (%f)", node.Name, utilization)
 			continue
 		}
+
+		if blockingPod := simulator.FindBlockingPod(nodeInfo.Pods()); blockingPod != nil {
+			glog.V(3).Infof("Skipping %s - pod %s/%s is not safe to evict", node.Name, blockingPod.Namespace, blockingPod.Name)
+			recordScaleDownDisabled(context, node, scaleDownDisabledNodes,
+				fmt.Sprintf("pod %s/%s is annotated with %s=false", blockingPod.Namespace, blockingPod.Name, simulator.SafeToEvictAnnotation))
+			continue
+		}
 		currentlyUnneededNodes = append(currentlyUnneededNodes, node)
 	}
 
 	// Phase2 - check which nodes can be probably removed using fast drain.
 	nodesToRemove, err := simulator.FindNodesToRemove(currentlyUnneededNodes, nodes, pods,
-		nil, predicateChecker,
+		podDisruptionBudgets, nil, context.PredicateChecker,
 		len(currentlyUnneededNodes), true)
 	if err != nil {
 		glog.Errorf("Error while simulating node drains: %v", err)
@@ -88,8 +130,8 @@ func FindUnneededNodes(nodes []*kube_api.Node,
 	// Update the timestamp map.
 	now := time.Now()
 	result := make(map[string]time.Time)
-	for _, node := range nodesToRemove {
-		name := node.Name
+	for _, nodeToRemove := range nodesToRemove {
+		name := nodeToRemove.Node.Name
 		if val, found := unneededNodes[name]; !found {
 			result[name] = now
 		} else {
@@ -99,78 +141,429 @@ func FindUnneededNodes(nodes []*kube_api.Node,
 	return result
 }
 
+// recordScaleDownDisabled remembers why the node is skipped in scale down and records a
+// ScaleDownDisabled event, unless the node was skipped for the same reason in the previous loop.
+func recordScaleDownDisabled(context *AutoscalingContext, node *kube_api.Node, disabledNodes map[string]string, reason string) {
+	disabledNodes[node.Name] = reason
+	if context.ScaleDownDisabledNodes[node.Name] != reason {
+		context.Recorder.Event(node, kube_api.EventTypeNormal, "ScaleDownDisabled", reason)
+	}
+}
+
 // ScaleDown tries to scale down the cluster. It returns ScaleDownResult indicating if any node was
 // removed and error if such occured.
 func ScaleDown(
+	context *AutoscalingContext,
 	nodes []*kube_api.Node,
 	unneededNodes map[string]time.Time,
-	unneededTime time.Duration,
 	pods []*kube_api.Pod,
-	gceManager *gce.GceManager,
-	client *kube_client.Client,
-	predicateChecker *simulator.PredicateChecker) (ScaleDownResult, error) {
+	podDisruptionBudgets []*policy.PodDisruptionBudget) (ScaleDownResult, error) {
+
+	candidates, removalBudget, _ := getScaleDownCandidates(context, nodes, unneededNodes, time.Now())
+	if len(candidates) == 0 {
+		glog.Infof("No candidates for scale down")
+		return ScaleDownNoUnneeded, nil
+	}
+
+	// Trying to delete empty nodes in bulk. If there are no empty nodes then CA will
+	// try to delete not-so-empty nodes.
+	emptyNodes := simulator.FindEmptyNodesToRemove(candidates, pods)
+	emptyNodes = limitToRemovalBudget(context, emptyNodes, removalBudget, context.MaxEmptyBulkDelete)
+	if len(emptyNodes) > 0 {
+		toRemove := make([]simulator.NodeToBeRemoved, 0, len(emptyNodes))
+		for _, node := range emptyNodes {
+			glog.Infof("Removing empty node %s", node.Name)
+			toRemove = append(toRemove, simulator.NodeToBeRemoved{Node: node})
+		}
+		if err := deleteNodesInParallel(context, toRemove); err != nil {
+			return ScaleDownError, err
+		}
+		return ScaleDownNodeDeleted, nil
+	}
+
+	nodesToRemove, err := simulator.FindNodesToRemove(candidates, nodes, pods, podDisruptionBudgets,
+		context.KubeClient, context.PredicateChecker, context.MaxNonEmptyBulkDelete, false)
+	if err != nil {
+		return ScaleDownError, fmt.Errorf("Find node to remove failed: %v", err)
+	}
+	nodesToRemove = limitNodesToRemoveToRemovalBudget(context, nodesToRemove, removalBudget)
+	if len(nodesToRemove) == 0 {
+		glog.V(1).Infof("No node to remove")
+		return ScaleDownNoNodeDeleted, nil
+	}
+	for _, toRemove := range nodesToRemove {
+		glog.Infof("Removing %s", toRemove.Node.Name)
+	}
+	if err := deleteNodesInParallel(context, nodesToRemove); err != nil {
+		return ScaleDownError, err
+	}
+
+	return ScaleDownNodeDeleted, nil
+}
+
+// getScaleDownCandidates returns the unneeded nodes that may be removed now, because they were
+// unneeded for long enough and their node groups are healthy and above min size. It also returns
+// the number of nodes that can be removed from each node group without going below its min size,
+// and the reasons why the other unneeded nodes were skipped, keyed by node name.
+func getScaleDownCandidates(context *AutoscalingContext, nodes []*kube_api.Node,
+	unneededNodes map[string]time.Time, now time.Time) ([]*kube_api.Node, map[string]int, map[string]string) {
 
-	now := time.Now()
 	candidates := make([]*kube_api.Node, 0)
+	removalBudget := make(map[string]int)
+	skipped := make(map[string]string)
 	for _, node := range nodes {
 		if val, found := unneededNodes[node.Name]; found {
 
 			glog.V(2).Infof("%s was unneeded for %s", node.Name, now.Sub(val).String())
 
-			// Check how long the node was underutilized.
-			if !val.Add(unneededTime).Before(now) {
+			nodeGroup, err := context.CloudProvider.NodeGroupForNode(node)
+			if err != nil {
+				glog.Errorf("Error while checking node group for %s: %v", node.Name, err)
+				skipped[node.Name] = fmt.Sprintf("failed to get node group: %v", err)
 				continue
 			}
 
-			// Check mig size.
-			instance, err := config.InstanceConfigFromProviderId(node.Spec.ProviderID)
-			if err != nil {
-				glog.Errorf("Error while parsing providerid of %s: %v", node.Name, err)
+			// Check how long the node was underutilized.
+			if !val.Add(context.GetScaleDownUnneededTime(nodeGroup.Id())).Before(now) {
+				skipped[node.Name] = "not unneeded for long enough"
 				continue
 			}
-			migConfig, err := gceManager.GetMigForInstance(instance)
-			if err != nil {
-				glog.Errorf("Error while checking mig config for instance %v: %v", instance, err)
+
+			// Check node group size.
+			if context.UnhealthyNodeGroups[nodeGroup.Id()] {
+				glog.V(1).Infof("Skipping %s - node group %s is unhealthy", node.Name, nodeGroup.Id())
+				skipped[node.Name] = fmt.Sprintf("node group %s is unhealthy", nodeGroup.Id())
 				continue
 			}
-			size, err := gceManager.GetMigSize(migConfig)
+			size, err := nodeGroup.TargetSize()
 			if err != nil {
-				glog.Errorf("Error while checking mig size for instance %v: %v", instance, err)
+				glog.Errorf("Error while checking node group size %s: %v", nodeGroup.Id(), err)
+				skipped[node.Name] = fmt.Sprintf("failed to get size of %s: %v", nodeGroup.Id(), err)
 				continue
 			}
 
-			if size <= int64(migConfig.MinSize) {
-				glog.V(1).Infof("Skipping %s - mig min size reached", node.Name)
+			if size <= nodeGroup.MinSize() {
+				glog.V(1).Infof("Skipping %s - node group min size reached", node.Name)
+				skipped[node.Name] = fmt.Sprintf("%s min size reached", nodeGroup.Id())
 				continue
 			}
 
+			removalBudget[nodeGroup.Id()] = size - nodeGroup.MinSize()
 			candidates = append(candidates, node)
 		}
 	}
-	if len(candidates) == 0 {
-		glog.Infof("No candidates for scale down")
-		return ScaleDownNoUnneeded, nil
+	return candidates, removalBudget, skipped
+}
+
+// limitToRemovalBudget returns at most maxCount nodes from the list, skipping nodes whose
+// removal would take their node group below its min size. The budget is updated accordingly.
+func limitToRemovalBudget(context *AutoscalingContext, nodes []*kube_api.Node, removalBudget map[string]int,
+	maxCount int) []*kube_api.Node {
+	result := make([]*kube_api.Node, 0)
+	for _, node := range nodes {
+		if len(result) >= maxCount {
+			break
+		}
+		nodeGroup, err := context.CloudProvider.NodeGroupForNode(node)
+		if err != nil {
+			glog.Errorf("Error while checking node group for %s: %v", node.Name, err)
+			continue
+		}
+		if removalBudget[nodeGroup.Id()] <= 0 {
+			glog.V(1).Infof("Skipping %s - node group min size reached", node.Name)
+			continue
+		}
+		removalBudget[nodeGroup.Id()]--
+		result = append(result, node)
+	}
+	return result
+}
+
+// limitNodesToRemoveToRemovalBudget is limitToRemovalBudget for simulator.NodeToBeRemoved.
+// Removing a subset of nodes returned by the simulator is always safe.
+func limitNodesToRemoveToRemovalBudget(context *AutoscalingContext, nodesToRemove []simulator.NodeToBeRemoved,
+	removalBudget map[string]int) []simulator.NodeToBeRemoved {
+	nodes := make([]*kube_api.Node, 0, len(nodesToRemove))
+	byName := make(map[string]simulator.NodeToBeRemoved)
+	for _, toRemove := range nodesToRemove {
+		nodes = append(nodes, toRemove.Node)
+		byName[toRemove.Node.Name] = toRemove
+	}
+	result := make([]simulator.NodeToBeRemoved, 0)
+	for _, node := range limitToRemovalBudget(context, nodes, removalBudget, len(nodes)) {
+		result = append(result, byName[node.Name])
+	}
+	return result
+}
+
+// deleteNodesInParallel drains and deletes all given nodes concurrently. It returns an error
+// if any of the deletions failed.
+func deleteNodesInParallel(context *AutoscalingContext, nodesToRemove []simulator.NodeToBeRemoved) error {
+	errors := make(chan error, len(nodesToRemove))
+	for _, toRemove := range nodesToRemove {
+		go func(toRemove simulator.NodeToBeRemoved) {
+			errors <- deleteNode(context, toRemove.Node, toRemove.PodsToReschedule)
+		}(toRemove)
 	}
+	var firstErr error
+	for range nodesToRemove {
+		if err := <-errors; err != nil {
+			glog.Errorf("Scale down failed: %v", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
 
-	nodesToRemove, err := simulator.FindNodesToRemove(candidates, nodes, pods, client, predicateChecker, 1, false)
+// deleteNode cordons the node, evicts the given pods and deletes the node through the cloud
+// provider. If any step fails the node is brought back to service.
+func deleteNode(context *AutoscalingContext, node *kube_api.Node, pods []*kube_api.Pod) error {
+	nodeGroup, err := context.CloudProvider.NodeGroupForNode(node)
 	if err != nil {
-		return ScaleDownError, fmt.Errorf("Find node to remove failed: %v", err)
+		return fmt.Errorf("Failed to get node group for %s: %v", node.Name, err)
 	}
-	if len(nodesToRemove) == 0 {
-		glog.V(1).Infof("No node to remove")
-		return ScaleDownNoNodeDeleted, nil
+
+	if err := drainNode(context, node, pods); err != nil {
+		context.Recorder.Eventf(node, kube_api.EventTypeWarning, "ScaleDownFailed", "failed to drain the node: %v", err)
+		if uncordonErr := uncordonNode(context.KubeClient, node.Name); uncordonErr != nil {
+			glog.Errorf("Failed to uncordon %s: %v", node.Name, uncordonErr)
+		}
+		return fmt.Errorf("Failed to drain %s: %v", node.Name, err)
+	}
+
+	if err := nodeGroup.DeleteNodes([]*kube_api.Node{node}); err != nil {
+		context.Recorder.Eventf(node, kube_api.EventTypeWarning, "ScaleDownFailed", "failed to delete the node: %v", err)
+		if uncordonErr := uncordonNode(context.KubeClient, node.Name); uncordonErr != nil {
+			glog.Errorf("Failed to uncordon %s: %v", node.Name, uncordonErr)
+		}
+		return fmt.Errorf("Failed to delete %s: %v", node.Name, err)
 	}
-	nodeToRemove := nodesToRemove[0]
-	glog.Infof("Removing %s", nodeToRemove.Name)
-	instanceConfig, err := config.InstanceConfigFromProviderId(nodeToRemove.Spec.ProviderID)
+	context.Recorder.Eventf(node, kube_api.EventTypeNormal, "ScaleDown", "node removed by cluster autoscaler")
+	scaledDownNodesCount.WithLabelValues(nodeGroup.Id()).Inc()
+	context.StatusTracker.RegisterScaleDown(nodeGroup.Id(), time.Now())
+	return nil
+}
+
+// drainNode marks the node unschedulable, evicts the given pods respecting their termination
+// grace periods and PodDisruptionBudgets, and waits up to MaxDrainTime for them to leave the node
+// and for their controllers to schedule replacements on other nodes.
+func drainNode(context *AutoscalingContext, node *kube_api.Node, pods []*kube_api.Pod) error {
+	if err := cordonNode(context.KubeClient, node.Name); err != nil {
+		return fmt.Errorf("failed to cordon: %v", err)
+	}
+
+	replacements, err := newReplacementTracker(context.KubeClient, pods)
 	if err != nil {
-		return ScaleDownError, fmt.Errorf("Failed to get instance config for %s: %v", nodeToRemove.Name, err)
+		return fmt.Errorf("failed to list the pods of the controllers: %v", err)
 	}
 
-	err = gceManager.DeleteInstances([]*config.InstanceConfig{instanceConfig})
+	for _, pod := range pods {
+		context.Recorder.Eventf(pod, kube_api.EventTypeNormal, "ScaleDown", "evicting pod for node scale down")
+		if err := evictPod(context.KubeClient, pod); err != nil {
+			return err
+		}
+	}
+
+	return wait.PollImmediate(podEvictionPollInterval, context.MaxDrainTime, func() (bool, error) {
+		for _, pod := range pods {
+			current, err := context.KubeClient.Pods(pod.Namespace).Get(pod.Name)
+			if err != nil {
+				if kube_errors.IsNotFound(err) {
+					continue
+				}
+				glog.Warningf("Failed to check %s/%s: %v", pod.Namespace, pod.Name, err)
+				return false, nil
+			}
+			// Pods recreated with the same name (e.g. by a PetSet) are fine as long as they
+			// landed on a different node.
+			if current.UID == pod.UID && current.Spec.NodeName == node.Name {
+				glog.V(4).Infof("Waiting for %s/%s to leave %s", pod.Namespace, pod.Name, node.Name)
+				return false, nil
+			}
+		}
+		return replacements.scheduled(context.KubeClient, node.Name), nil
+	})
+}
+
+// evictPod evicts the pod through the eviction subresource, so that the api server checks the
+// PodDisruptionBudgets of the pod. The vendored client doesn't know the Eviction type, so the
+// request body is built by hand.
+func evictPod(client *kube_client.Client, pod *kube_api.Pod) error {
+	eviction := map[string]interface{}{
+		"apiVersion": "policy/v1beta1",
+		"kind":       "Eviction",
+		"metadata": map[string]string{
+			"namespace": pod.Namespace,
+			"name":      pod.Name,
+		},
+	}
+	if pod.Spec.TerminationGracePeriodSeconds != nil {
+		eviction["deleteOptions"] = map[string]interface{}{
+			"gracePeriodSeconds": *pod.Spec.TerminationGracePeriodSeconds,
+		}
+	}
+	body, err := json.Marshal(eviction)
 	if err != nil {
-		return ScaleDownError, fmt.Errorf("Failed to delete %v: %v", instanceConfig, err)
+		return err
+	}
+	err = client.Post().Namespace(pod.Namespace).Resource("pods").Name(pod.Name).SubResource("eviction").
+		Body(body).Do().Error()
+	if err == nil || kube_errors.IsNotFound(err) {
+		return nil
 	}
+	if status, ok := err.(kube_errors.APIStatus); ok && status.Status().Code == kube_errors.StatusTooManyRequests {
+		return fmt.Errorf("eviction of %s/%s not allowed by its disruption budget: %v", pod.Namespace, pod.Name, err)
+	}
+	return fmt.Errorf("failed to evict %s/%s: %v", pod.Namespace, pod.Name, err)
+}
 
-	return ScaleDownNodeDeleted, nil
+// replacementTracker counts the pods evicted from each controller and remembers the pods that
+// the controllers had before the eviction, to recognize the replacements created afterwards.
+type replacementTracker struct {
+	// evicted is the number of evicted pods per controller, keyed by controllerKey.
+	evicted map[string]int
+	// namespaces of the controllers.
+	namespaces map[string]bool
+	// known are the pods of the controllers that existed before the eviction.
+	known map[types.UID]bool
+}
+
+func newReplacementTracker(client *kube_client.Client, pods []*kube_api.Pod) (*replacementTracker, error) {
+	tracker := &replacementTracker{
+		evicted:    make(map[string]int),
+		namespaces: make(map[string]bool),
+		known:      make(map[types.UID]bool),
+	}
+	for _, pod := range pods {
+		key, err := controllerKey(pod)
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			// Pods without controller are not replaced, it's enough that they leave the node.
+			continue
+		}
+		tracker.evicted[key]++
+		tracker.namespaces[pod.Namespace] = true
+	}
+	for namespace := range tracker.namespaces {
+		podList, err := client.Pods(namespace).List(kube_api.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, pod := range podList.Items {
+			tracker.known[pod.UID] = true
+		}
+	}
+	return tracker, nil
+}
+
+// scheduled checks whether each controller has scheduled as many new pods outside of the node
+// as were evicted from it.
+func (tracker *replacementTracker) scheduled(client *kube_client.Client, nodeName string) bool {
+	replaced := make(map[string]int)
+	for namespace := range tracker.namespaces {
+		podList, err := client.Pods(namespace).List(kube_api.ListOptions{})
+		if err != nil {
+			glog.Warningf("Failed to list pods in %s: %v", namespace, err)
+			return false
+		}
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			if tracker.known[pod.UID] || pod.DeletionTimestamp != nil ||
+				pod.Spec.NodeName == "" || pod.Spec.NodeName == nodeName {
+				continue
+			}
+			key, err := controllerKey(pod)
+			if err != nil {
+				glog.Warningf("Failed to get the controller of %s/%s: %v", pod.Namespace, pod.Name, err)
+				continue
+			}
+			replaced[key]++
+		}
+	}
+	for key, count := range tracker.evicted {
+		if replaced[key] < count {
+			glog.V(4).Infof("Waiting for %s to schedule %d replacement pods, %d scheduled", key, count, replaced[key])
+			return false
+		}
+	}
+	return true
+}
+
+// controllerKey identifies the controller of the pod, or is empty if the pod has no controller.
+func controllerKey(pod *kube_api.Pod) (string, error) {
+	ref, err := simulator.GetControllerRef(pod, kube_api.Codecs.UniversalDecoder())
+	if err != nil || ref == nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s/%s", ref.Kind, pod.Namespace, ref.Name), nil
+}
+
+// cordonNode marks the node unschedulable and remembers that it was done by cluster autoscaler.
+func cordonNode(client *kube_client.Client, nodeName string) error {
+	return updateNode(client, nodeName, func(node *kube_api.Node) bool {
+		if node.Spec.Unschedulable {
+			// Cordoned by someone else, nothing to undo later.
+			return false
+		}
+		node.Spec.Unschedulable = true
+		if node.Annotations == nil {
+			node.Annotations = make(map[string]string)
+		}
+		node.Annotations[CordonedByAutoscalerAnnotation] = time.Now().Format(time.RFC3339)
+		return true
+	})
+}
+
+// uncordonNode reverts cordonNode. Nodes cordoned by someone else are left untouched.
+func uncordonNode(client *kube_client.Client, nodeName string) error {
+	return updateNode(client, nodeName, func(node *kube_api.Node) bool {
+		if _, found := node.Annotations[CordonedByAutoscalerAnnotation]; !found {
+			return false
+		}
+		delete(node.Annotations, CordonedByAutoscalerAnnotation)
+		node.Spec.Unschedulable = false
+		return true
+	})
+}
+
+// updateNode fetches the node, applies the mutation and updates the node if mutate returned true.
+// Conflicting updates are retried.
+func updateNode(client *kube_client.Client, nodeName string, mutate func(*kube_api.Node) bool) error {
+	var err error
+	for i := 0; i < maxNodeUpdateRetries; i++ {
+		var node *kube_api.Node
+		node, err = client.Nodes().Get(nodeName)
+		if err != nil {
+			return err
+		}
+		if !mutate(node) {
+			return nil
+		}
+		if _, err = client.Nodes().Update(node); err == nil || !kube_errors.IsConflict(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// CleanUpCordonedNodes uncordons all nodes left cordoned by a previous cluster autoscaler run,
+// for example one that was killed in the middle of a drain.
+func CleanUpCordonedNodes(client *kube_client.Client) error {
+	nodes, err := client.Nodes().List(kube_api.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes.Items {
+		if _, found := node.Annotations[CordonedByAutoscalerAnnotation]; found {
+			glog.Infof("Uncordoning %s left cordoned by a previous scale down", node.Name)
+			if err := uncordonNode(client, node.Name); err != nil {
+				glog.Errorf("Failed to uncordon %s: %v", node.Name, err)
+			}
+		}
+	}
+	return nil
 }
@@ -17,13 +17,23 @@ limitations under the License.
 package main
 
 import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	testprovider "k8s.io/contrib/cluster-autoscaler/cloudprovider/test"
 	"k8s.io/contrib/cluster-autoscaler/simulator"
 	. "k8s.io/contrib/cluster-autoscaler/utils/test"
 
 	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/apis/extensions"
+	"k8s.io/kubernetes/pkg/apis/policy"
+	kube_record "k8s.io/kubernetes/pkg/client/record"
+	"k8s.io/kubernetes/pkg/runtime"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -55,19 +65,359 @@ func TestFindUnneededNodes(t *testing.T) {
 	n3 := BuildTestNode("n3", 1000, 10)
 	n4 := BuildTestNode("n4", 10000, 10)
 
-	result := FindUnneededNodes([]*kube_api.Node{n1, n2, n3, n4}, map[string]time.Time{}, 0.35,
-		[]*kube_api.Pod{p1, p2, p3, p4}, simulator.NewTestPredicateChecker())
+	context := &AutoscalingContext{
+		Recorder:                      kube_record.NewFakeRecorder(10),
+		PredicateChecker:              simulator.NewTestPredicateChecker(),
+		ScaleDownUtilizationThreshold: 0.35,
+	}
+	result := FindUnneededNodes(context, []*kube_api.Node{n1, n2, n3, n4}, map[string]time.Time{},
+		[]*kube_api.Pod{p1, p2, p3, p4}, []*policy.PodDisruptionBudget{})
 
 	assert.Equal(t, 1, len(result))
 	addTime, found := result["n2"]
 	assert.True(t, found)
 
 	result["n1"] = time.Now()
-	result2 := FindUnneededNodes([]*kube_api.Node{n1, n2, n3, n4}, result, 0.35,
-		[]*kube_api.Pod{p1, p2, p3, p4}, simulator.NewTestPredicateChecker())
+	result2 := FindUnneededNodes(context, []*kube_api.Node{n1, n2, n3, n4}, result,
+		[]*kube_api.Pod{p1, p2, p3, p4}, []*policy.PodDisruptionBudget{})
 
 	assert.Equal(t, 1, len(result2))
 	addTime2, found := result2["n2"]
 	assert.True(t, found)
 	assert.Equal(t, addTime, addTime2)
 }
+
+func TestFindUnneededNodesPerNodeGroupThreshold(t *testing.T) {
+	p1 := BuildTestPod("p1", 500, 0)
+	p1.Spec.NodeName = "n1"
+	p1.Annotations = map[string]string{
+		"kubernetes.io/created-by": "{\"kind\":\"SerializedReference\",\"apiVersion\":\"v1\",\"reference\":{\"kind\":\"ReplicaSet\"}}",
+	}
+
+	p2 := BuildTestPod("p2", 500, 0)
+	p2.Spec.NodeName = "n2"
+	p2.Annotations = map[string]string{
+		"kubernetes.io/created-by": "{\"kind\":\"SerializedReference\",\"apiVersion\":\"v1\",\"reference\":{\"kind\":\"ReplicaSet\"}}",
+	}
+
+	n1 := BuildTestNode("n1", 1000, 10)
+	n2 := BuildTestNode("n2", 1000, 10)
+	n3 := BuildTestNode("n3", 10000, 10)
+
+	p3 := BuildTestPod("p3", 5000, 0)
+	p3.Spec.NodeName = "n3"
+
+	ng1Threshold := 0.6
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 2)
+	provider.AddNodeGroup("ng2", 1, 10, 2)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng2", n2)
+	provider.AddNode("ng2", n3)
+
+	context := &AutoscalingContext{
+		CloudProvider:                 provider,
+		Recorder:                      kube_record.NewFakeRecorder(10),
+		PredicateChecker:              simulator.NewTestPredicateChecker(),
+		ScaleDownUtilizationThreshold: 0.35,
+		NodeGroupScaleDownOptions: map[string]NodeGroupScaleDownOptions{
+			"ng1": {ScaleDownUtilizationThreshold: &ng1Threshold},
+		},
+	}
+	result := FindUnneededNodes(context, []*kube_api.Node{n1, n2, n3}, map[string]time.Time{},
+		[]*kube_api.Pod{p1, p2, p3}, []*policy.PodDisruptionBudget{})
+
+	assert.Equal(t, 1, len(result))
+	_, found := result["n1"]
+	assert.True(t, found)
+
+	// An explicit zero threshold disables scale down of the node group.
+	ng1Threshold = 0
+	result = FindUnneededNodes(context, []*kube_api.Node{n1, n2, n3}, map[string]time.Time{},
+		[]*kube_api.Pod{p1, p2, p3}, []*policy.PodDisruptionBudget{})
+	assert.Equal(t, 0, len(result))
+}
+
+func TestFindUnneededNodesOptOut(t *testing.T) {
+	replicaSetRef := "{\"kind\":\"SerializedReference\",\"apiVersion\":\"v1\",\"reference\":{\"kind\":\"ReplicaSet\"}}"
+
+	p1 := BuildTestPod("p1", 100, 0)
+	p1.Spec.NodeName = "n1"
+	p1.Annotations = map[string]string{"kubernetes.io/created-by": replicaSetRef}
+
+	p2 := BuildTestPod("p2", 100, 0)
+	p2.Spec.NodeName = "n2"
+	p2.Annotations = map[string]string{
+		"kubernetes.io/created-by":      replicaSetRef,
+		simulator.SafeToEvictAnnotation: "false",
+	}
+
+	// Unreplicated pod with local storage, explicitly marked as safe to evict.
+	p3 := BuildTestPod("p3", 100, 0)
+	p3.Spec.NodeName = "n3"
+	p3.Spec.Volumes = []kube_api.Volume{{Name: "cache", VolumeSource: kube_api.VolumeSource{EmptyDir: &kube_api.EmptyDirVolumeSource{}}}}
+	p3.Annotations = map[string]string{simulator.SafeToEvictAnnotation: "true"}
+
+	p4 := BuildTestPod("p4", 100, 0)
+	p4.Spec.NodeName = "n4"
+
+	n1 := BuildTestNode("n1", 1000, 10)
+	n1.Annotations = map[string]string{ScaleDownDisabledAnnotation: "true"}
+	n2 := BuildTestNode("n2", 1000, 10)
+	n3 := BuildTestNode("n3", 1000, 10)
+	n4 := BuildTestNode("n4", 10000, 10)
+
+	recorder := kube_record.NewFakeRecorder(10)
+	context := &AutoscalingContext{
+		Recorder:                      recorder,
+		PredicateChecker:              simulator.NewTestPredicateChecker(),
+		ScaleDownUtilizationThreshold: 0.35,
+	}
+	result := FindUnneededNodes(context, []*kube_api.Node{n1, n2, n3, n4}, map[string]time.Time{},
+		[]*kube_api.Pod{p1, p2, p3, p4}, []*policy.PodDisruptionBudget{})
+
+	_, found := result["n3"]
+	assert.True(t, found)
+	_, found = result["n1"]
+	assert.False(t, found)
+	_, found = result["n2"]
+	assert.False(t, found)
+
+	assert.Contains(t, <-recorder.Events, ScaleDownDisabledAnnotation)
+	assert.Contains(t, <-recorder.Events, "default/p2")
+
+	// The events are not recorded again while the nodes stay opted out.
+	FindUnneededNodes(context, []*kube_api.Node{n1, n2, n3, n4}, result,
+		[]*kube_api.Pod{p1, p2, p3, p4}, []*policy.PodDisruptionBudget{})
+	assert.Equal(t, 0, len(recorder.Events))
+
+	// Opting out again after opting in records the event again.
+	n1.Annotations = nil
+	FindUnneededNodes(context, []*kube_api.Node{n1, n2, n3, n4}, result,
+		[]*kube_api.Pod{p1, p2, p3, p4}, []*policy.PodDisruptionBudget{})
+	n1.Annotations = map[string]string{ScaleDownDisabledAnnotation: "true"}
+	FindUnneededNodes(context, []*kube_api.Node{n1, n2, n3, n4}, result,
+		[]*kube_api.Pod{p1, p2, p3, p4}, []*policy.PodDisruptionBudget{})
+	assert.Equal(t, 1, len(recorder.Events))
+	assert.Contains(t, <-recorder.Events, ScaleDownDisabledAnnotation)
+}
+
+func TestScaleDown(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000000)
+	n2 := BuildTestNode("n2", 1000, 1000000)
+
+	p1 := BuildTestPod("p1", 100, 0)
+	p1.Annotations = map[string]string{
+		"kubernetes.io/created-by": "{\"kind\":\"SerializedReference\",\"apiVersion\":\"v1\",\"reference\":{\"kind\":\"ReplicaSet\",\"namespace\":\"default\",\"name\":\"rs\"}}",
+	}
+	p1.Spec.NodeName = "n1"
+
+	p2 := BuildTestPod("p2", 800, 0)
+	p2.Spec.NodeName = "n2"
+
+	replacement := BuildTestPod("p1-replacement", 100, 0)
+	replacement.UID = "p1-replacement"
+	replacement.Annotations = p1.Annotations
+	replacement.Spec.NodeName = "n2"
+
+	var updatedNode *kube_api.Node
+	deletedPods := make([]string, 0)
+	client, server := NewTestKubeClient(func(req *http.Request) runtime.Object {
+		switch req.Method + " " + req.URL.Path {
+		case "GET /api/v1/pods":
+			return BuildTestPodList(p1)
+		case "GET /apis/extensions/v1beta1/namespaces/default/replicasets/rs":
+			return &extensions.ReplicaSet{ObjectMeta: kube_api.ObjectMeta{Namespace: "default", Name: "rs"}}
+		case "GET /api/v1/nodes/n1":
+			return n1
+		case "PUT /api/v1/nodes/n1":
+			updatedNode = DecodeTestObject(req, &kube_api.Node{}).(*kube_api.Node)
+			return updatedNode
+		case "POST /api/v1/namespaces/default/pods/p1/eviction":
+			deletedPods = append(deletedPods, "p1")
+			return &unversioned.Status{Status: unversioned.StatusSuccess}
+		case "GET /api/v1/namespaces/default/pods":
+			if len(deletedPods) == 0 {
+				return BuildTestPodList(p1, p2)
+			}
+			// The replica set replaced p1 on n2.
+			return BuildTestPodList(p2, replacement)
+		}
+		return nil
+	})
+	defer server.Close()
+
+	deleted := make([]string, 0)
+	provider := testprovider.NewTestCloudProvider(nil, func(id string, node string) error {
+		deleted = append(deleted, node)
+		return nil
+	})
+	provider.AddNodeGroup("ng1", 1, 10, 2)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng1", n2)
+
+	context := &AutoscalingContext{
+		CloudProvider:         provider,
+		StatusTracker:         NewStatusTracker(),
+		KubeClient:            client,
+		Recorder:              kube_record.NewFakeRecorder(10),
+		PredicateChecker:      simulator.NewTestPredicateChecker(),
+		ScaleDownUnneededTime: time.Minute,
+		MaxEmptyBulkDelete:    10,
+		MaxNonEmptyBulkDelete: 1,
+		MaxDrainTime:          time.Second,
+	}
+	result, err := ScaleDown(context, []*kube_api.Node{n1, n2},
+		map[string]time.Time{"n1": time.Now().Add(-5 * time.Minute)}, []*kube_api.Pod{p1, p2}, []*policy.PodDisruptionBudget{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, ScaleDownNodeDeleted, result)
+	assert.Equal(t, []string{"n1"}, deleted)
+	assert.Equal(t, []string{"p1"}, deletedPods)
+	assert.True(t, updatedNode.Spec.Unschedulable)
+	size, _ := provider.GetNodeGroup("ng1").TargetSize()
+	assert.Equal(t, 1, size)
+}
+
+func TestScaleDownDrainFailure(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000000)
+	n2 := BuildTestNode("n2", 1000, 1000000)
+
+	p1 := BuildTestPod("p1", 100, 0)
+	p1.Annotations = map[string]string{
+		"kubernetes.io/created-by": "{\"kind\":\"SerializedReference\",\"apiVersion\":\"v1\",\"reference\":{\"kind\":\"ReplicaSet\",\"namespace\":\"default\",\"name\":\"rs\"}}",
+	}
+	p1.Spec.NodeName = "n1"
+
+	p2 := BuildTestPod("p2", 800, 0)
+	p2.Spec.NodeName = "n2"
+
+	testCases := []struct {
+		description string
+		eviction    runtime.Object
+		pod         runtime.Object
+		wantErr     string
+	}{
+		{"the pod never terminates", &unversioned.Status{Status: unversioned.StatusSuccess}, p1, "timed out"},
+		{"the pod is not replaced", &unversioned.Status{Status: unversioned.StatusSuccess}, nil, "timed out"},
+		{"the disruption budget doesn't allow the eviction",
+			&unversioned.Status{Status: unversioned.StatusFailure, Code: 429, Reason: unversioned.StatusReasonTimeout}, nil,
+			"disruption budget"},
+	}
+	for _, tc := range testCases {
+		currentNode := n1
+		client, server := NewTestKubeClient(func(req *http.Request) runtime.Object {
+			switch req.Method + " " + req.URL.Path {
+			case "GET /api/v1/pods":
+				return BuildTestPodList(p1)
+			case "GET /apis/extensions/v1beta1/namespaces/default/replicasets/rs":
+				return &extensions.ReplicaSet{ObjectMeta: kube_api.ObjectMeta{Namespace: "default", Name: "rs"}}
+			case "GET /api/v1/nodes/n1":
+				return currentNode
+			case "PUT /api/v1/nodes/n1":
+				currentNode = DecodeTestObject(req, &kube_api.Node{}).(*kube_api.Node)
+				return currentNode
+			case "POST /api/v1/namespaces/default/pods/p1/eviction":
+				return tc.eviction
+			case "GET /api/v1/namespaces/default/pods/p1":
+				return tc.pod
+			case "GET /api/v1/namespaces/default/pods":
+				return BuildTestPodList(p2)
+			}
+			return nil
+		})
+
+		provider := testprovider.NewTestCloudProvider(nil, func(id string, node string) error {
+			t.Fatalf("unexpected deletion of %s when %s", node, tc.description)
+			return nil
+		})
+		provider.AddNodeGroup("ng1", 1, 10, 2)
+		provider.AddNode("ng1", n1)
+		provider.AddNode("ng1", n2)
+
+		context := &AutoscalingContext{
+			CloudProvider:         provider,
+			StatusTracker:         NewStatusTracker(),
+			KubeClient:            client,
+			Recorder:              kube_record.NewFakeRecorder(10),
+			PredicateChecker:      simulator.NewTestPredicateChecker(),
+			ScaleDownUnneededTime: time.Minute,
+			MaxEmptyBulkDelete:    10,
+			MaxNonEmptyBulkDelete: 1,
+			MaxDrainTime:          100 * time.Millisecond,
+		}
+		result, err := ScaleDown(context, []*kube_api.Node{n1, n2},
+			map[string]time.Time{"n1": time.Now().Add(-5 * time.Minute)}, []*kube_api.Pod{p1, p2}, []*policy.PodDisruptionBudget{})
+		server.Close()
+
+		if assert.Error(t, err, tc.description) {
+			assert.Contains(t, err.Error(), tc.wantErr, tc.description)
+		}
+		assert.Equal(t, ScaleDownError, result, tc.description)
+		assert.False(t, currentNode.Spec.Unschedulable, tc.description)
+		_, found := currentNode.Annotations[CordonedByAutoscalerAnnotation]
+		assert.False(t, found, tc.description)
+	}
+}
+
+func TestScaleDownEmptyBulk(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000000)
+	n2 := BuildTestNode("n2", 1000, 1000000)
+	n3 := BuildTestNode("n3", 1000, 1000000)
+	n4 := BuildTestNode("n4", 1000, 1000000)
+	nodes := map[string]*kube_api.Node{"n1": n1, "n2": n2, "n3": n3, "n4": n4}
+
+	p1 := BuildTestPod("p1", 800, 0)
+	p1.Spec.NodeName = "n4"
+
+	var lock sync.Mutex
+	client, server := NewTestKubeClient(func(req *http.Request) runtime.Object {
+		lock.Lock()
+		defer lock.Unlock()
+		parts := strings.Split(req.URL.Path, "/")
+		if len(parts) == 5 && parts[3] == "nodes" {
+			if req.Method == "PUT" {
+				nodes[parts[4]] = DecodeTestObject(req, &kube_api.Node{}).(*kube_api.Node)
+			}
+			return nodes[parts[4]]
+		}
+		return nil
+	})
+	defer server.Close()
+
+	deleted := make([]string, 0)
+	provider := testprovider.NewTestCloudProvider(nil, func(id string, node string) error {
+		lock.Lock()
+		defer lock.Unlock()
+		deleted = append(deleted, node)
+		return nil
+	})
+	provider.AddNodeGroup("ng1", 1, 10, 3)
+	provider.AddNodeGroup("ng2", 0, 10, 1)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng1", n2)
+	provider.AddNode("ng1", n3)
+	provider.AddNode("ng2", n4)
+
+	context := &AutoscalingContext{
+		CloudProvider:         provider,
+		StatusTracker:         NewStatusTracker(),
+		KubeClient:            client,
+		Recorder:              kube_record.NewFakeRecorder(10),
+		PredicateChecker:      simulator.NewTestPredicateChecker(),
+		ScaleDownUnneededTime: time.Minute,
+		MaxEmptyBulkDelete:    10,
+		MaxNonEmptyBulkDelete: 1,
+		MaxDrainTime:          time.Second,
+	}
+	unneededSince := time.Now().Add(-5 * time.Minute)
+	result, err := ScaleDown(context, []*kube_api.Node{n1, n2, n3, n4},
+		map[string]time.Time{"n1": unneededSince, "n2": unneededSince, "n3": unneededSince, "n4": unneededSince},
+		[]*kube_api.Pod{p1}, []*policy.PodDisruptionBudget{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, ScaleDownNodeDeleted, result)
+	// Only two nodes from ng1 can be removed without going below its min size.
+	sort.Strings(deleted)
+	assert.Equal(t, []string{"n1", "n2"}, deleted)
+}
@@ -18,29 +18,22 @@ package main
 
 import (
 	"fmt"
+	"math"
+	"strings"
+	"time"
 
-	"k8s.io/contrib/cluster-autoscaler/config"
 	"k8s.io/contrib/cluster-autoscaler/estimator"
+	"k8s.io/contrib/cluster-autoscaler/expander"
 	"k8s.io/contrib/cluster-autoscaler/simulator"
-	"k8s.io/contrib/cluster-autoscaler/utils/gce"
 	kube_api "k8s.io/kubernetes/pkg/api"
-	kube_record "k8s.io/kubernetes/pkg/client/record"
-	kube_client "k8s.io/kubernetes/pkg/client/unversioned"
+	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
 
 	"github.com/golang/glog"
 )
 
-// ExpansionOption describes an option to expand the cluster.
-type ExpansionOption struct {
-	migConfig *config.MigConfig
-	estimator *estimator.BasicNodeEstimator
-}
-
 // ScaleUp tries to scale the cluster up. Return true if it found a way to increase the size,
 // false if it didn't and error if an error occured.
-func ScaleUp(unschedulablePods []*kube_api.Pod, nodes []*kube_api.Node, migConfigs []*config.MigConfig,
-	gceManager *gce.GceManager, kubeClient *kube_client.Client,
-	predicateChecker *simulator.PredicateChecker, recorder kube_record.EventRecorder) (bool, error) {
+func ScaleUp(context *AutoscalingContext, unschedulablePods []*kube_api.Pod, nodes []*kube_api.Node) (bool, error) {
 
 	// From now on we only care about unschedulable pods that were marked after the newest
 	// node became available for the scheduler.
@@ -53,94 +46,252 @@ func ScaleUp(unschedulablePods []*kube_api.Pod, nodes []*kube_api.Node, migConfi
 		glog.V(1).Infof("Pod %s/%s is unschedulable", pod.Namespace, pod.Name)
 	}
 
-	expansionOptions := make([]ExpansionOption, 0)
-	nodeInfos, err := GetNodeInfosForMigs(nodes, gceManager, kubeClient)
+	expansionOptions := make([]expander.Option, 0)
+	nodeInfos, err := GetNodeInfosForGroups(nodes, context.CloudProvider, context.RequiredPodsLister)
 	if err != nil {
-		return false, fmt.Errorf("failed to build node infors for migs: %v", err)
+		return false, fmt.Errorf("failed to build node infos for node groups: %v", err)
 	}
 
-	podsRemainUnshedulable := make(map[*kube_api.Pod]struct{})
-	for _, migConfig := range migConfigs {
+	resourcesLeft, err := getResourcesLeft(context, nodeInfos)
+	if err != nil {
+		return false, fmt.Errorf("failed to check cluster-wide limits: %v", err)
+	}
+	// Reasons why the pods don't fit the node groups, in the form "<node group>: <reason>".
+	podsRemainUnshedulable := make(map[*kube_api.Pod][]string)
+	// Pods that no node group could take because of the cluster-wide limits.
+	podsOverLimit := make(map[*kube_api.Pod]kube_api.ResourceName)
+	// Pods of each capped option that don't fit the capped node count, keyed by node group id.
+	podsLimitedInGroup := make(map[string]map[*kube_api.Pod]kube_api.ResourceName)
+	for _, nodeGroup := range context.CloudProvider.NodeGroups() {
 
-		currentSize, err := gceManager.GetMigSize(migConfig)
+		currentSize, err := nodeGroup.TargetSize()
 		if err != nil {
-			glog.Errorf("Failed to get MIG size: %v", err)
+			glog.Errorf("Failed to get node group size: %v", err)
+			continue
+		}
+		if currentSize >= nodeGroup.MaxSize() {
+			// skip this node group.
+			glog.V(4).Infof("Skipping node group %s - max size reached", nodeGroup.Id())
 			continue
 		}
-		if currentSize >= int64(migConfig.MaxSize) {
-			// skip this mig.
-			glog.V(4).Infof("Skipping MIG %s - max size reached", migConfig.Url())
+		if context.UnhealthyNodeGroups[nodeGroup.Id()] {
+			glog.V(4).Infof("Skipping node group %s - unhealthy", nodeGroup.Id())
+			continue
+		}
+		if context.ScaleUpTracker.IsBackedOff(nodeGroup.Id(), time.Now()) {
+			glog.V(4).Infof("Skipping node group %s - scale up backoff", nodeGroup.Id())
 			continue
 		}
 
-		option := ExpansionOption{
-			migConfig: migConfig,
-			estimator: estimator.NewBasicNodeEstimator(),
+		option := expander.Option{
+			NodeGroup: nodeGroup,
+			Pods:      make([]*kube_api.Pod, 0),
 		}
-		migHelpsSomePods := false
 
-		nodeInfo, found := nodeInfos[migConfig.Url()]
+		nodeInfo, found := nodeInfos[nodeGroup.Id()]
 		if !found {
-			glog.Errorf("No node info for: %s", migConfig.Url())
+			glog.Errorf("No node info for: %s", nodeGroup.Id())
 			continue
 		}
 
 		for _, pod := range unschedulablePods {
-			err = predicateChecker.CheckPredicates(pod, nodeInfo)
+			err = context.PredicateChecker.CheckAllPredicates(pod, nodeInfo)
 			if err == nil {
-				migHelpsSomePods = true
-				option.estimator.Add(pod)
+				option.Pods = append(option.Pods, pod)
 			} else {
 				glog.V(2).Infof("Scale-up predicate failed: %v", err)
-				podsRemainUnshedulable[pod] = struct{}{}
+				reason := err.Error()
+				if predicateErr, ok := err.(*simulator.PredicateError); ok {
+					reason = predicateErr.Reason()
+				}
+				podsRemainUnshedulable[pod] = append(podsRemainUnshedulable[pod], fmt.Sprintf("%s: %s", nodeGroup.Id(), reason))
 			}
 		}
-		if migHelpsSomePods {
+		if len(option.Pods) > 0 {
+			switch context.EstimatorName {
+			case estimator.BinpackingEstimatorName:
+				binpackingEstimator := estimator.NewBinpackingNodeEstimator(context.PredicateChecker)
+				for _, pod := range option.Pods {
+					binpackingEstimator.Add(pod)
+				}
+				option.NodeCount, option.Debug = binpackingEstimator.Estimate(nodeInfo)
+				glog.V(1).Info(binpackingEstimator.GetDebug())
+			case estimator.BasicEstimatorName:
+				basicEstimator := estimator.NewBasicNodeEstimator()
+				for _, pod := range option.Pods {
+					basicEstimator.Add(pod)
+				}
+				option.NodeCount, option.Debug = basicEstimator.Estimate(nodeInfo.Node())
+				glog.V(1).Info(basicEstimator.GetDebug())
+			default:
+				return false, fmt.Errorf("unknown estimator: %s", context.EstimatorName)
+			}
+			if maxCount, limitingResource := resourcesLeft.maxNodeCount(nodeInfo.Node()); option.NodeCount > maxCount {
+				glog.V(1).Infof("Capping %s scale up from %d to %d nodes - cluster-wide %s limit",
+					nodeGroup.Id(), option.NodeCount, maxCount, limitingResource)
+				option.NodeCount = maxCount
+				if maxCount == 0 {
+					for _, pod := range option.Pods {
+						podsOverLimit[pod] = limitingResource
+					}
+					continue
+				}
+				binpackingEstimator := estimator.NewBinpackingNodeEstimator(context.PredicateChecker)
+				for _, pod := range option.Pods {
+					binpackingEstimator.Add(pod)
+				}
+				podsLimitedInGroup[nodeGroup.Id()] = make(map[*kube_api.Pod]kube_api.ResourceName)
+				for _, pod := range binpackingEstimator.PodsNotFitting(nodeInfo, maxCount) {
+					podsLimitedInGroup[nodeGroup.Id()][pod] = limitingResource
+				}
+			}
 			expansionOptions = append(expansionOptions, option)
 		}
 	}
 
 	// Pick some expansion option.
-	bestOption := BestExpansionOption(expansionOptions)
-	if bestOption != nil && bestOption.estimator.GetCount() > 0 {
-		glog.V(1).Infof("Best option to resize: %s", bestOption.migConfig.Url())
-		nodeInfo, found := nodeInfos[bestOption.migConfig.Url()]
-		if !found {
-			return false, fmt.Errorf("no sample node for: %s", bestOption.migConfig.Url())
+	bestOption := context.ExpanderStrategy.BestOption(expansionOptions, nodeInfos)
+	if bestOption != nil && bestOption.NodeCount > 0 {
+		glog.V(1).Infof("Best option to resize: %s", bestOption.NodeGroup.Id())
+		glog.V(1).Info(bestOption.Debug)
+		glog.V(1).Infof("Estimated %d nodes needed in %s", bestOption.NodeCount, bestOption.NodeGroup.Id())
 
-		}
-		node := nodeInfo.Node()
-		estimate, report := bestOption.estimator.Estimate(node)
-		glog.V(1).Info(bestOption.estimator.GetDebug())
-		glog.V(1).Info(report)
-		glog.V(1).Infof("Estimated %d nodes needed in %s", estimate, bestOption.migConfig.Url())
-
-		currentSize, err := gceManager.GetMigSize(bestOption.migConfig)
+		currentSize, err := bestOption.NodeGroup.TargetSize()
 		if err != nil {
-			return false, fmt.Errorf("failed to get MIG size: %v", err)
+			return false, fmt.Errorf("failed to get node group size: %v", err)
 		}
-		newSize := currentSize + int64(estimate)
-		if newSize >= int64(bestOption.migConfig.MaxSize) {
-			glog.V(1).Infof("Capping size to MAX (%d)", bestOption.migConfig.MaxSize)
-			newSize = int64(bestOption.migConfig.MaxSize)
+		scaleUps := []*nodeGroupScaleUp{{nodeGroup: bestOption.NodeGroup, currentSize: currentSize, newSize: currentSize}}
+		if context.BalanceSimilarNodeGroups {
+			for _, option := range expansionOptions {
+				if option.NodeGroup.Id() == bestOption.NodeGroup.Id() ||
+					!IsNodeInfoSimilar(nodeInfos[bestOption.NodeGroup.Id()], nodeInfos[option.NodeGroup.Id()]) {
+					continue
+				}
+				size, err := option.NodeGroup.TargetSize()
+				if err != nil {
+					glog.Errorf("Failed to get node group size: %v", err)
+					continue
+				}
+				glog.V(1).Infof("Balancing scale up of %s with similar node group %s", bestOption.NodeGroup.Id(), option.NodeGroup.Id())
+				scaleUps = append(scaleUps, &nodeGroupScaleUp{nodeGroup: option.NodeGroup, currentSize: size, newSize: size})
+			}
 		}
-		glog.V(1).Infof("Setting %s size to %d", bestOption.migConfig.Url(), newSize)
+		balanceScaleUp(scaleUps, bestOption.NodeCount)
 
-		if err := gceManager.SetMigSize(bestOption.migConfig, newSize); err != nil {
-			return false, fmt.Errorf("failed to set MIG size: %v", err)
+		for _, scaleUp := range scaleUps {
+			if scaleUp.newSize <= scaleUp.currentSize {
+				continue
+			}
+			if scaleUp.newSize >= scaleUp.nodeGroup.MaxSize() {
+				glog.V(1).Infof("Capping %s size to MAX (%d)", scaleUp.nodeGroup.Id(), scaleUp.nodeGroup.MaxSize())
+			}
+			glog.V(1).Infof("Setting %s size to %d", scaleUp.nodeGroup.Id(), scaleUp.newSize)
+			if err := scaleUp.nodeGroup.SetTargetSize(scaleUp.newSize); err != nil {
+				return false, fmt.Errorf("failed to set node group size: %v", err)
+			}
+			scaleUpCount.WithLabelValues(scaleUp.nodeGroup.Id()).Inc()
+			scaledUpNodesCount.WithLabelValues(scaleUp.nodeGroup.Id()).Add(float64(scaleUp.newSize - scaleUp.currentSize))
+			context.StatusTracker.RegisterScaleUp(scaleUp.nodeGroup.Id(), time.Now())
+			context.ScaleUpTracker.RegisterScaleUp(scaleUp.nodeGroup.Id(), scaleUp.newSize, time.Now())
 		}
 
-		for pod := range bestOption.estimator.FittingPods {
-			recorder.Eventf(pod, kube_api.EventTypeNormal, "TriggeredScaleUp",
-				"pod triggered scale-up, mig: %s, sizes (current/new): %d/%d", bestOption.migConfig.Name, currentSize, newSize)
+		for _, pod := range bestOption.Pods {
+			if len(scaleUps) == 1 {
+				context.Recorder.Eventf(pod, kube_api.EventTypeNormal, "TriggeredScaleUp",
+					"pod triggered scale-up, group: %s, sizes (current/new): %d/%d", bestOption.NodeGroup.Id(), currentSize, scaleUps[0].newSize)
+			} else {
+				context.Recorder.Eventf(pod, kube_api.EventTypeNormal, "TriggeredScaleUp",
+					"pod triggered scale-up, groups with sizes (current/new): %s", describeScaleUps(scaleUps))
+			}
+			if limitingResource, found := podsLimitedInGroup[bestOption.NodeGroup.Id()][pod]; found {
+				context.Recorder.Eventf(pod, kube_api.EventTypeWarning, "ScaleUpLimited",
+					"scale-up of group %s limited by the cluster-wide %s limit, pod may stay pending", bestOption.NodeGroup.Id(), limitingResource)
+			}
 		}
 
 		return true, nil
 	}
-	for pod := range podsRemainUnshedulable {
-		recorder.Event(pod, kube_api.EventTypeNormal, "NotTriggerScaleUp",
-			"pod didn't trigger scale-up (it wouldn't fit if a new node is added)")
+	for pod, reasons := range podsRemainUnshedulable {
+		if _, found := podsOverLimit[pod]; found {
+			continue
+		}
+		context.Recorder.Eventf(pod, kube_api.EventTypeNormal, "NotTriggerScaleUp",
+			"pod didn't trigger scale-up (it wouldn't fit if a new node is added): %s", strings.Join(reasons, "; "))
+	}
+	for pod, limitingResource := range podsOverLimit {
+		context.Recorder.Eventf(pod, kube_api.EventTypeWarning, "NotTriggerScaleUp",
+			"pod didn't trigger scale-up (cluster-wide %s limit reached)", limitingResource)
 	}
 
 	return false, nil
 }
+
+// resourcesLeft is the amount of cores and memory that can still be added to the cluster.
+// Negative values mean no limit.
+type resourcesLeft struct {
+	milliCores int64
+	memory     int64
+}
+
+// getResourcesLeft computes how many cores and memory can be added to the node groups without exceeding
+// the cluster-wide limits. The size of each node group is its target size times the capacity of its
+// template node. If a limit is set and the size of a node group is unknown, an error is returned so that
+// the limit is never exceeded.
+func getResourcesLeft(context *AutoscalingContext, nodeInfos map[string]*schedulercache.NodeInfo) (resourcesLeft, error) {
+	left := resourcesLeft{milliCores: -1, memory: -1}
+	if context.MaxTotalCores > 0 {
+		left.milliCores = context.MaxTotalCores * 1000
+	}
+	if context.MaxTotalMemory > 0 {
+		left.memory = context.MaxTotalMemory
+	}
+	if left.milliCores < 0 && left.memory < 0 {
+		return left, nil
+	}
+	for _, nodeGroup := range context.CloudProvider.NodeGroups() {
+		size, err := nodeGroup.TargetSize()
+		if err != nil {
+			return resourcesLeft{}, fmt.Errorf("failed to get size of %s: %v", nodeGroup.Id(), err)
+		}
+		if size == 0 {
+			continue
+		}
+		nodeInfo, found := nodeInfos[nodeGroup.Id()]
+		if !found {
+			return resourcesLeft{}, fmt.Errorf("no node info for %s", nodeGroup.Id())
+		}
+		capacity := nodeInfo.Node().Status.Capacity
+		if left.milliCores >= 0 {
+			left.milliCores = max64(0, left.milliCores-int64(size)*capacity.Cpu().MilliValue())
+		}
+		if left.memory >= 0 {
+			left.memory = max64(0, left.memory-int64(size)*capacity.Memory().Value())
+		}
+	}
+	return left, nil
+}
+
+// maxNodeCount returns how many nodes like the given one can be added without exceeding the
+// cluster-wide limits, together with the resource that limits the count. The count is
+// math.MaxInt32 if there is no limit.
+func (left resourcesLeft) maxNodeCount(node *kube_api.Node) (int, kube_api.ResourceName) {
+	result := int64(math.MaxInt32)
+	var limitingResource kube_api.ResourceName
+	capacity := node.Status.Capacity
+	if cores := capacity.Cpu().MilliValue(); left.milliCores >= 0 && cores > 0 && left.milliCores/cores < result {
+		result = left.milliCores / cores
+		limitingResource = kube_api.ResourceCPU
+	}
+	if memory := capacity.Memory().Value(); left.memory >= 0 && memory > 0 && left.memory/memory < result {
+		result = left.memory / memory
+		limitingResource = kube_api.ResourceMemory
+	}
+	return int(result), limitingResource
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
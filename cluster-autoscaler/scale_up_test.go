@@ -0,0 +1,331 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	testprovider "k8s.io/contrib/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/contrib/cluster-autoscaler/estimator"
+	"k8s.io/contrib/cluster-autoscaler/expander"
+	"k8s.io/contrib/cluster-autoscaler/simulator"
+	. "k8s.io/contrib/cluster-autoscaler/utils/test"
+
+	kube_api "k8s.io/kubernetes/pkg/api"
+	kube_record "k8s.io/kubernetes/pkg/client/record"
+	"k8s.io/kubernetes/pkg/runtime"
+	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScaleUpOK(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000000)
+	n2 := BuildTestNode("n2", 4000, 1000000)
+
+	p1 := BuildTestPod("p1", 800, 0)
+	p1.Spec.NodeName = "n1"
+
+	client, server := NewTestKubeClient(func(req *http.Request) runtime.Object {
+		if req.URL.Path == "/api/v1/pods" {
+			return BuildTestPodList()
+		}
+		return nil
+	})
+	defer server.Close()
+
+	scaledUp := make(map[string]int)
+	provider := testprovider.NewTestCloudProvider(func(id string, size int) error {
+		scaledUp[id] = size
+		return nil
+	}, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng2", n2)
+
+	p2 := BuildTestPod("p2", 2000, 0)
+	p3 := BuildTestPod("p3", 500, 0)
+	context := &AutoscalingContext{
+		CloudProvider:      provider,
+		StatusTracker:      NewStatusTracker(),
+		ScaleUpTracker:     NewScaleUpTracker(time.Minute),
+		KubeClient:         client,
+		RequiredPodsLister: simulator.NewClientRequiredPodsLister(client),
+		Recorder:           kube_record.NewFakeRecorder(5),
+		PredicateChecker:   simulator.NewTestPredicateChecker(),
+		ExpanderStrategy:   expander.NewMostPodsStrategy(),
+		EstimatorName:      estimator.BinpackingEstimatorName,
+	}
+	result, err := ScaleUp(context, []*kube_api.Pod{p2, p3}, []*kube_api.Node{n1, n2})
+
+	assert.NoError(t, err)
+	assert.True(t, result)
+	assert.Equal(t, map[string]int{"ng2": 2}, scaledUp)
+}
+
+func TestScaleUpFromZero(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000000)
+
+	client, server := NewTestKubeClient(func(req *http.Request) runtime.Object {
+		if req.URL.Path == "/api/v1/pods" {
+			return BuildTestPodList()
+		}
+		return nil
+	})
+	defer server.Close()
+
+	scaledUp := make(map[string]int)
+	provider := testprovider.NewTestCloudProvider(func(id string, size int) error {
+		scaledUp[id] = size
+		return nil
+	}, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNode("ng1", n1)
+	ng2 := provider.AddNodeGroup("ng2", 0, 10, 0)
+	template := schedulercache.NewNodeInfo()
+	template.SetNode(BuildTestNode("ng2-template", 4000, 1000000))
+	ng2.SetTemplateNodeInfo(template)
+
+	p1 := BuildTestPod("p1", 3000, 0)
+	context := &AutoscalingContext{
+		CloudProvider:      provider,
+		StatusTracker:      NewStatusTracker(),
+		ScaleUpTracker:     NewScaleUpTracker(time.Minute),
+		KubeClient:         client,
+		RequiredPodsLister: simulator.NewClientRequiredPodsLister(client),
+		Recorder:           kube_record.NewFakeRecorder(5),
+		PredicateChecker:   simulator.NewTestPredicateChecker(),
+		ExpanderStrategy:   expander.NewRandomStrategy(),
+		EstimatorName:      estimator.BinpackingEstimatorName,
+	}
+	result, err := ScaleUp(context, []*kube_api.Pod{p1}, []*kube_api.Node{n1})
+
+	assert.NoError(t, err)
+	assert.True(t, result)
+	assert.Equal(t, map[string]int{"ng2": 1}, scaledUp)
+}
+
+func TestScaleUpNoHelp(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000000)
+
+	client, server := NewTestKubeClient(func(req *http.Request) runtime.Object {
+		if req.URL.Path == "/api/v1/pods" {
+			return BuildTestPodList()
+		}
+		return nil
+	})
+	defer server.Close()
+
+	provider := testprovider.NewTestCloudProvider(func(id string, size int) error {
+		t.Fatalf("unexpected scale up of %s", id)
+		return nil
+	}, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNode("ng1", n1)
+
+	p1 := BuildTestPod("p1", 2000, 0)
+	p1.Spec.NodeSelector = map[string]string{"gpu": "true"}
+	recorder := kube_record.NewFakeRecorder(5)
+	context := &AutoscalingContext{
+		CloudProvider:      provider,
+		StatusTracker:      NewStatusTracker(),
+		ScaleUpTracker:     NewScaleUpTracker(time.Minute),
+		KubeClient:         client,
+		RequiredPodsLister: simulator.NewClientRequiredPodsLister(client),
+		Recorder:           recorder,
+		PredicateChecker:   simulator.NewTestPredicateChecker(),
+		ExpanderStrategy:   expander.NewRandomStrategy(),
+		EstimatorName:      estimator.BasicEstimatorName,
+	}
+	result, err := ScaleUp(context, []*kube_api.Pod{p1}, []*kube_api.Node{n1})
+
+	assert.NoError(t, err)
+	assert.False(t, result)
+	event := <-recorder.Events
+	assert.Contains(t, event, "NotTriggerScaleUp")
+	assert.Contains(t, event, "ng1: MatchNodeSelector, Insufficient cpu")
+}
+
+func TestScaleUpSkipsBackedOffGroup(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000000)
+	n2 := BuildTestNode("n2", 4000, 1000000)
+
+	client, server := NewTestKubeClient(func(req *http.Request) runtime.Object {
+		if req.URL.Path == "/api/v1/pods" {
+			return BuildTestPodList()
+		}
+		return nil
+	})
+	defer server.Close()
+
+	scaledUp := make(map[string]int)
+	provider := testprovider.NewTestCloudProvider(func(id string, size int) error {
+		scaledUp[id] = size
+		return nil
+	}, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNode("ng1", n1)
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	provider.AddNode("ng2", n2)
+
+	// ng1 failed to provide a node.
+	now := time.Now()
+	tracker := NewScaleUpTracker(time.Minute)
+	tracker.RegisterScaleUp("ng1", 2, now.Add(-2*time.Minute))
+	tracker.CheckNodeProvisioning(provider, []*kube_api.Node{n1, n2}, now)
+
+	p1 := BuildTestPod("p1", 500, 0)
+	context := &AutoscalingContext{
+		CloudProvider:      provider,
+		StatusTracker:      NewStatusTracker(),
+		ScaleUpTracker:     tracker,
+		KubeClient:         client,
+		RequiredPodsLister: simulator.NewClientRequiredPodsLister(client),
+		Recorder:           kube_record.NewFakeRecorder(5),
+		PredicateChecker:   simulator.NewTestPredicateChecker(),
+		ExpanderStrategy:   expander.NewRandomStrategy(),
+		EstimatorName:      estimator.BinpackingEstimatorName,
+	}
+	result, err := ScaleUp(context, []*kube_api.Pod{p1}, []*kube_api.Node{n1, n2})
+
+	assert.NoError(t, err)
+	assert.True(t, result)
+	assert.Equal(t, map[string]int{"ng2": 2}, scaledUp)
+}
+
+func TestScaleUpResourceLimits(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000000)
+	n2 := BuildTestNode("n2", 4000, 1000000)
+
+	client, server := NewTestKubeClient(func(req *http.Request) runtime.Object {
+		if req.URL.Path == "/api/v1/pods" {
+			return BuildTestPodList()
+		}
+		return nil
+	})
+	defer server.Close()
+
+	scaledUp := make(map[string]int)
+	provider := testprovider.NewTestCloudProvider(func(id string, size int) error {
+		scaledUp[id] = size
+		return nil
+	}, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNode("ng1", n1)
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	provider.AddNode("ng2", n2)
+
+	p1 := BuildTestPod("p1", 3000, 0)
+	p2 := BuildTestPod("p2", 3000, 0)
+	p3 := BuildTestPod("p3", 3000, 0)
+	recorder := kube_record.NewFakeRecorder(10)
+	context := &AutoscalingContext{
+		CloudProvider:      provider,
+		StatusTracker:      NewStatusTracker(),
+		ScaleUpTracker:     NewScaleUpTracker(time.Minute),
+		KubeClient:         client,
+		RequiredPodsLister: simulator.NewClientRequiredPodsLister(client),
+		Recorder:           recorder,
+		PredicateChecker:   simulator.NewTestPredicateChecker(),
+		ExpanderStrategy:   expander.NewRandomStrategy(),
+		EstimatorName:      estimator.BinpackingEstimatorName,
+		MaxTotalCores:      13,
+	}
+	result, err := ScaleUp(context, []*kube_api.Pod{p1, p2, p3}, []*kube_api.Node{n1, n2})
+
+	assert.NoError(t, err)
+	assert.True(t, result)
+	assert.Equal(t, map[string]int{"ng2": 3}, scaledUp)
+	// Only the pod that doesn't fit the two added nodes is told about the limit.
+	close(recorder.Events)
+	triggered, limited := 0, 0
+	for event := range recorder.Events {
+		if strings.Contains(event, "TriggeredScaleUp") {
+			triggered++
+		}
+		if strings.Contains(event, "ScaleUpLimited") {
+			limited++
+			assert.Contains(t, event, "cluster-wide cpu limit")
+		}
+	}
+	assert.Equal(t, 3, triggered)
+	assert.Equal(t, 1, limited)
+
+	// The cluster has 13 cores now, no more nodes can be added.
+	scaledUp = make(map[string]int)
+	recorder = kube_record.NewFakeRecorder(10)
+	context.Recorder = recorder
+	result, err = ScaleUp(context, []*kube_api.Pod{p1}, []*kube_api.Node{n1, n2})
+
+	assert.NoError(t, err)
+	assert.False(t, result)
+	assert.Equal(t, map[string]int{}, scaledUp)
+	assert.Contains(t, <-recorder.Events, "cluster-wide cpu limit reached")
+}
+
+func TestScaleUpResourceLimitsUnknownUsage(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000000)
+
+	client, server := NewTestKubeClient(func(req *http.Request) runtime.Object {
+		if req.URL.Path == "/api/v1/pods" {
+			return BuildTestPodList()
+		}
+		return nil
+	})
+	defer server.Close()
+
+	scaledUp := make(map[string]int)
+	provider := testprovider.NewTestCloudProvider(func(id string, size int) error {
+		scaledUp[id] = size
+		return nil
+	}, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNode("ng1", n1)
+	// The nodes of ng2 are not registered yet and it has no template, so its usage is unknown.
+	provider.AddNodeGroup("ng2", 1, 10, 3)
+
+	p1 := BuildTestPod("p1", 500, 0)
+	context := &AutoscalingContext{
+		CloudProvider:      provider,
+		StatusTracker:      NewStatusTracker(),
+		ScaleUpTracker:     NewScaleUpTracker(time.Minute),
+		KubeClient:         client,
+		RequiredPodsLister: simulator.NewClientRequiredPodsLister(client),
+		Recorder:           kube_record.NewFakeRecorder(10),
+		PredicateChecker:   simulator.NewTestPredicateChecker(),
+		ExpanderStrategy:   expander.NewRandomStrategy(),
+		EstimatorName:      estimator.BinpackingEstimatorName,
+		MaxTotalCores:      100,
+	}
+	result, err := ScaleUp(context, []*kube_api.Pod{p1}, []*kube_api.Node{n1})
+
+	assert.Error(t, err)
+	assert.False(t, result)
+	assert.Equal(t, map[string]int{}, scaledUp)
+
+	// Without a limit the usage doesn't matter.
+	context.MaxTotalCores = 0
+	result, err = ScaleUp(context, []*kube_api.Pod{p1}, []*kube_api.Node{n1})
+
+	assert.NoError(t, err)
+	assert.True(t, result)
+	assert.Equal(t, map[string]int{"ng1": 2}, scaledUp)
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/contrib/cluster-autoscaler/cloudprovider"
+	kube_api "k8s.io/kubernetes/pkg/api"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// InitialScaleUpBackoff is the time a node group is excluded from scale up after its
+	// first failed scale up.
+	InitialScaleUpBackoff = 5 * time.Minute
+	// MaxScaleUpBackoff is the maximum time a node group is excluded from scale up.
+	MaxScaleUpBackoff = 30 * time.Minute
+)
+
+// scaleUpRequest is a scale up of a node group that hasn't provided all its nodes yet.
+type scaleUpRequest struct {
+	expectedSize int
+	time         time.Time
+}
+
+// scaleUpBackoff describes the failed scale ups of a node group.
+type scaleUpBackoff struct {
+	failures int
+	until    time.Time
+}
+
+// ScaleUpTracker remembers the number of nodes requested from each node group and checks
+// whether they register in Kubernetes in time. Node groups that fail to provide the nodes
+// are excluded from scale up for an exponentially growing period, so that other expansion
+// options are tried instead.
+type ScaleUpTracker struct {
+	sync.Mutex
+	maxNodeProvisionTime time.Duration
+	requests             map[string]*scaleUpRequest
+	backoffs             map[string]*scaleUpBackoff
+}
+
+// NewScaleUpTracker builds new ScaleUpTracker.
+func NewScaleUpTracker(maxNodeProvisionTime time.Duration) *ScaleUpTracker {
+	return &ScaleUpTracker{
+		maxNodeProvisionTime: maxNodeProvisionTime,
+		requests:             make(map[string]*scaleUpRequest),
+		backoffs:             make(map[string]*scaleUpBackoff),
+	}
+}
+
+// SetMaxNodeProvisionTime changes the time after which requested nodes are considered failed.
+func (tracker *ScaleUpTracker) SetMaxNodeProvisionTime(maxNodeProvisionTime time.Duration) {
+	tracker.Lock()
+	defer tracker.Unlock()
+	tracker.maxNodeProvisionTime = maxNodeProvisionTime
+}
+
+// RegisterScaleUp records that the node group was resized to the given size.
+func (tracker *ScaleUpTracker) RegisterScaleUp(nodeGroupId string, newSize int, now time.Time) {
+	tracker.Lock()
+	defer tracker.Unlock()
+	tracker.requests[nodeGroupId] = &scaleUpRequest{
+		expectedSize: newSize,
+		time:         now,
+	}
+}
+
+// IsBackedOff returns true if the node group shouldn't be scaled up at the given time.
+func (tracker *ScaleUpTracker) IsBackedOff(nodeGroupId string, now time.Time) bool {
+	tracker.Lock()
+	defer tracker.Unlock()
+	backoff, found := tracker.backoffs[nodeGroupId]
+	return found && now.Before(backoff.until)
+}
+
+// PendingScaleUps returns the ids of the node groups whose requested nodes haven't registered yet
+// and whose provision time hasn't run out.
+func (tracker *ScaleUpTracker) PendingScaleUps() []string {
+	tracker.Lock()
+	defer tracker.Unlock()
+	result := make([]string, 0, len(tracker.requests))
+	for id := range tracker.requests {
+		result = append(result, id)
+	}
+	return result
+}
+
+// CheckNodeProvisioning compares the requested node group sizes with the number of registered nodes.
+// Scale ups that provided all nodes are forgotten and reset the backoff of the node group. If some
+// nodes didn't register within the max node provision time, they are considered failed: the target
+// size of the node group is decreased by their number and the node group is put in backoff.
+func (tracker *ScaleUpTracker) CheckNodeProvisioning(cloudProvider cloudprovider.CloudProvider,
+	allNodes []*kube_api.Node, now time.Time) {
+
+	registered := make(map[string]int)
+	for _, node := range allNodes {
+		nodeGroup, err := cloudProvider.NodeGroupForNode(node)
+		if err != nil {
+			glog.V(4).Infof("Node %s not counted in scale up tracking: %v", node.Name, err)
+			continue
+		}
+		registered[nodeGroup.Id()]++
+	}
+
+	tracker.Lock()
+	defer tracker.Unlock()
+	nodeGroups := cloudProvider.NodeGroups()
+	tracker.forgetRemovedNodeGroups(nodeGroups)
+	for _, nodeGroup := range nodeGroups {
+		id := nodeGroup.Id()
+		request, found := tracker.requests[id]
+		if !found {
+			continue
+		}
+		if registered[id] >= request.expectedSize {
+			glog.V(2).Infof("All %d nodes requested from %s registered", request.expectedSize, id)
+			delete(tracker.requests, id)
+			delete(tracker.backoffs, id)
+			continue
+		}
+		if now.Sub(request.time) < tracker.maxNodeProvisionTime {
+			continue
+		}
+
+		missing := request.expectedSize - registered[id]
+		glog.Warningf("%d of %d nodes requested from %s didn't register within %v", missing,
+			request.expectedSize, id, tracker.maxNodeProvisionTime)
+		delete(tracker.requests, id)
+		tracker.backOff(id, now)
+
+		targetSize, err := nodeGroup.TargetSize()
+		if err != nil {
+			glog.Errorf("Failed to get size of %s: %v", id, err)
+			continue
+		}
+		newSize := targetSize - missing
+		if newSize < registered[id] {
+			newSize = registered[id]
+		}
+		if newSize < nodeGroup.MinSize() {
+			newSize = nodeGroup.MinSize()
+		}
+		if newSize >= targetSize {
+			continue
+		}
+		glog.V(1).Infof("Decreasing %s size from %d to %d", id, targetSize, newSize)
+		if err := nodeGroup.SetTargetSize(newSize); err != nil {
+			glog.Errorf("Failed to decrease size of %s: %v", id, err)
+		}
+	}
+}
+
+// forgetRemovedNodeGroups drops the scale ups and backoffs of node groups that are no longer
+// controlled by the cloud provider.
+func (tracker *ScaleUpTracker) forgetRemovedNodeGroups(nodeGroups []cloudprovider.NodeGroup) {
+	ids := make(map[string]bool)
+	for _, nodeGroup := range nodeGroups {
+		ids[nodeGroup.Id()] = true
+	}
+	for id := range tracker.requests {
+		if !ids[id] {
+			delete(tracker.requests, id)
+		}
+	}
+	for id := range tracker.backoffs {
+		if !ids[id] {
+			delete(tracker.backoffs, id)
+		}
+	}
+}
+
+// backOff puts the node group in backoff, doubling the previous backoff duration.
+func (tracker *ScaleUpTracker) backOff(nodeGroupId string, now time.Time) {
+	backoff, found := tracker.backoffs[nodeGroupId]
+	if !found {
+		backoff = &scaleUpBackoff{}
+		tracker.backoffs[nodeGroupId] = backoff
+	}
+	duration := InitialScaleUpBackoff
+	for i := 0; i < backoff.failures && duration < MaxScaleUpBackoff; i++ {
+		duration *= 2
+	}
+	if duration > MaxScaleUpBackoff {
+		duration = MaxScaleUpBackoff
+	}
+	backoff.failures++
+	backoff.until = now.Add(duration)
+	glog.Warningf("Node group %s excluded from scale up until %s", nodeGroupId, backoff.until)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	testprovider "k8s.io/contrib/cluster-autoscaler/cloudprovider/test"
+	. "k8s.io/contrib/cluster-autoscaler/utils/test"
+
+	kube_api "k8s.io/kubernetes/pkg/api"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckNodeProvisioning(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000000)
+	n2 := BuildTestNode("n2", 1000, 1000000)
+	n3 := BuildTestNode("n3", 1000, 1000000)
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 4)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng1", n2)
+	provider.AddNodeGroup("ng2", 1, 10, 2)
+	provider.AddNode("ng2", n3)
+
+	now := time.Now()
+	tracker := NewScaleUpTracker(10 * time.Minute)
+	tracker.RegisterScaleUp("ng1", 4, now)
+	tracker.RegisterScaleUp("ng2", 2, now)
+	nodes := []*kube_api.Node{n1, n2, n3}
+
+	// Nodes are still provisioning.
+	tracker.CheckNodeProvisioning(provider, nodes, now.Add(5*time.Minute))
+	pending := tracker.PendingScaleUps()
+	sort.Strings(pending)
+	assert.Equal(t, []string{"ng1", "ng2"}, pending)
+	assert.False(t, tracker.IsBackedOff("ng1", now.Add(5*time.Minute)))
+	size, _ := provider.GetNodeGroup("ng1").TargetSize()
+	assert.Equal(t, 4, size)
+
+	// ng1 got one more node, the rest failed.
+	n4 := BuildTestNode("n4", 1000, 1000000)
+	provider.AddNode("ng1", n4)
+	nodes = append(nodes, n4)
+	later := now.Add(11 * time.Minute)
+	tracker.CheckNodeProvisioning(provider, nodes, later)
+	size, _ = provider.GetNodeGroup("ng1").TargetSize()
+	assert.Equal(t, 3, size)
+	size, _ = provider.GetNodeGroup("ng2").TargetSize()
+	assert.Equal(t, 1, size)
+	assert.True(t, tracker.IsBackedOff("ng1", later))
+	assert.True(t, tracker.IsBackedOff("ng1", later.Add(InitialScaleUpBackoff-time.Second)))
+	assert.False(t, tracker.IsBackedOff("ng1", later.Add(InitialScaleUpBackoff)))
+
+	// The next failure doubles the backoff.
+	tracker.RegisterScaleUp("ng1", 4, later)
+	tracker.CheckNodeProvisioning(provider, nodes, later.Add(11*time.Minute))
+	assert.True(t, tracker.IsBackedOff("ng1", later.Add(11*time.Minute+2*InitialScaleUpBackoff-time.Second)))
+
+	// A successful scale up resets it.
+	tracker.RegisterScaleUp("ng1", 3, later)
+	tracker.CheckNodeProvisioning(provider, nodes, later.Add(12*time.Minute))
+	assert.False(t, tracker.IsBackedOff("ng1", later.Add(12*time.Minute)))
+}
+
+func TestCheckNodeProvisioningRemovedNodeGroup(t *testing.T) {
+	now := time.Now()
+	tracker := NewScaleUpTracker(time.Minute)
+	tracker.RegisterScaleUp("removed", 3, now)
+	tracker.backOff("removed", now)
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	tracker.CheckNodeProvisioning(provider, []*kube_api.Node{}, now)
+
+	assert.Equal(t, []string{}, tracker.PendingScaleUps())
+	assert.False(t, tracker.IsBackedOff("removed", now))
+}
@@ -23,8 +23,8 @@ import (
 
 	kube_api "k8s.io/kubernetes/pkg/api"
 	"k8s.io/kubernetes/pkg/api/resource"
+	"k8s.io/kubernetes/pkg/apis/policy"
 	kube_client "k8s.io/kubernetes/pkg/client/unversioned"
-	cmd "k8s.io/kubernetes/pkg/kubectl/cmd"
 	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
 
 	"github.com/golang/glog"
@@ -38,10 +38,24 @@ var (
 		"If true cluster autoscaler will never delete nodes with pods with local storage, e.g. EmptyDir or HostPath")
 )
 
-// FindNodesToRemove finds nodes that can be removed.
+// NodeToBeRemoved contain information about a node that can be removed.
+type NodeToBeRemoved struct {
+	// Node to be removed.
+	Node *kube_api.Node
+	// PodsToReschedule contains pods on the node that should be rescheduled elsewhere.
+	PodsToReschedule []*kube_api.Pod
+}
+
+// FindNodesToRemove finds nodes that can be removed. Returns also information about good
+// rescheduling location for each of the pods. Nodes are evaluated one after another and
+// each of them is checked assuming that all previously returned nodes are already gone,
+// so all returned nodes can be removed together. A node is not returned if evicting its pods,
+// together with the pods of previously returned nodes, would violate any of the given
+// PodDisruptionBudgets. Pods moved in the simulation to a node that is returned later also
+// get a new place, so that removing all returned nodes together still leaves every pod a place.
 func FindNodesToRemove(candidates []*kube_api.Node, allNodes []*kube_api.Node, pods []*kube_api.Pod,
-	client *kube_client.Client, predicateChecker *PredicateChecker, maxCount int,
-	fastCheck bool) ([]*kube_api.Node, error) {
+	podDisruptionBudgets []*policy.PodDisruptionBudget, client *kube_client.Client,
+	predicateChecker *PredicateChecker, maxCount int, fastCheck bool) ([]NodeToBeRemoved, error) {
 
 	nodeNameToNodeInfo := schedulercache.CreateNodeNameToInfoMap(pods)
 	for _, node := range allNodes {
@@ -49,7 +63,9 @@ func FindNodesToRemove(candidates []*kube_api.Node, allNodes []*kube_api.Node, p
 			nodeInfo.SetNode(node)
 		}
 	}
-	result := make([]*kube_api.Node, 0)
+	result := make([]NodeToBeRemoved, 0)
+	removedNodes := make(map[string]bool)
+	budgets := newDisruptionBudgets(podDisruptionBudgets)
 
 	evaluationType := "Detailed evaluation"
 	if fastCheck {
@@ -75,32 +91,78 @@ candidateloop:
 				continue candidateloop
 			}
 		} else {
-			drainResult, _, _, err := cmd.GetPodsForDeletionOnNodeDrain(client, node.Name,
-				kube_api.Codecs.UniversalDecoder(), false, true)
-
+			podsToRemove, err = DetailedGetPodsForMove(node.Name, false, *skipNodesWithSystemPods,
+				*skipNodesWithLocalStorage, client, kube_api.Codecs.UniversalDecoder())
 			if err != nil {
 				glog.V(2).Infof("%s: node %s cannot be removed: %v", evaluationType, node.Name, err)
 				continue candidateloop
 			}
-			podsToRemove = make([]*kube_api.Pod, 0, len(drainResult))
-			for i := range drainResult {
-				podsToRemove = append(podsToRemove, &drainResult[i])
-			}
 		}
-		findProblems := findPlaceFor(node.Name, podsToRemove, allNodes, nodeNameToNodeInfo, predicateChecker)
+		// Pods moved to the node from the previously returned nodes need a new place as well.
+		// They are already accounted for in the budgets.
+		podsToRemove, _ = splitMovedPods(podsToRemove)
+		var movedPods []*kube_api.Pod
+		if nodeInfo, found := nodeNameToNodeInfo[node.Name]; found {
+			_, movedPods = splitMovedPods(nodeInfo.Pods())
+		}
+		if err := budgets.checkEviction(podsToRemove); err != nil {
+			glog.V(2).Infof("%s: node %s cannot be removed: %v", evaluationType, node.Name, err)
+			continue candidateloop
+		}
+		removedNodes[node.Name] = true
+		podsToPlace := append(append([]*kube_api.Pod{}, podsToRemove...), movedPods...)
+		findProblems := findPlaceFor(removedNodes, podsToPlace, allNodes, nodeNameToNodeInfo, predicateChecker)
 		if findProblems == nil {
-			result = append(result, node)
+			budgets.recordEviction(podsToRemove)
+			result = append(result, NodeToBeRemoved{
+				Node:             node,
+				PodsToReschedule: podsToRemove,
+			})
 			glog.V(2).Infof("%s: node %s may be removed", evaluationType, node.Name)
 			if len(result) >= maxCount {
 				break candidateloop
 			}
 		} else {
-			glog.V(2).Infof("%s: node %s is not suitable for removal %v", evaluationType, node.Name, err)
+			delete(removedNodes, node.Name)
+			glog.V(2).Infof("%s: node %s is not suitable for removal %v", evaluationType, node.Name, findProblems)
 		}
 	}
 	return result, nil
 }
 
+// splitMovedPods separates the pods running on a node from the pods moved there by findPlaceFor,
+// which have no node name set.
+func splitMovedPods(pods []*kube_api.Pod) (running []*kube_api.Pod, moved []*kube_api.Pod) {
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" {
+			moved = append(moved, pod)
+		} else {
+			running = append(running, pod)
+		}
+	}
+	return running, moved
+}
+
+// FindEmptyNodesToRemove finds empty nodes that can be removed, i.e. nodes running only
+// pods that don't need to be moved elsewhere (mirror and DaemonSet pods).
+func FindEmptyNodesToRemove(candidates []*kube_api.Node, pods []*kube_api.Pod) []*kube_api.Node {
+	nodeNameToNodeInfo := schedulercache.CreateNodeNameToInfoMap(pods)
+	result := make([]*kube_api.Node, 0)
+	for _, node := range candidates {
+		if nodeInfo, found := nodeNameToNodeInfo[node.Name]; found {
+			// Should block on all pods.
+			podsToRemove, err := FastGetPodsToMove(nodeInfo, true, true, true, kube_api.Codecs.UniversalDecoder())
+			if err == nil && len(podsToRemove) == 0 {
+				result = append(result, node)
+			}
+		} else {
+			// Node without pods.
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
 // CalculateUtilization calculates utilization of a node, defined as total amount of requested resources divided by capacity.
 func CalculateUtilization(node *kube_api.Node, nodeInfo *schedulercache.NodeInfo) (float64, error) {
 	cpu, err := calculateUtilizationOfResource(node, nodeInfo, kube_api.ResourceCPU)
@@ -133,8 +195,10 @@ func calculateUtilizationOfResource(node *kube_api.Node, nodeInfo *schedulercach
 	return float64(podsRequest.MilliValue()) / float64(nodeCapacity.MilliValue()), nil
 }
 
+// findPlaceFor checks whether all pods can be placed on the nodes other than the banned ones. On success
+// nodeInfos are updated with the new pod placements so that consecutive calls account for them.
 // TODO: We don't need to pass list of nodes here as they are already available in nodeInfos.
-func findPlaceFor(bannedNode string, pods []*kube_api.Pod, nodes []*kube_api.Node, nodeInfos map[string]*schedulercache.NodeInfo,
+func findPlaceFor(bannedNodes map[string]bool, pods []*kube_api.Pod, nodes []*kube_api.Node, nodeInfos map[string]*schedulercache.NodeInfo,
 	predicateChecker *PredicateChecker) error {
 
 	newNodeInfos := make(map[string]*schedulercache.NodeInfo)
@@ -151,7 +215,7 @@ func findPlaceFor(bannedNode string, pods []*kube_api.Pod, nodes []*kube_api.Nod
 		// TODO: Sort nodes by utilization
 	nodeloop:
 		for _, node := range nodes {
-			if node.Name == bannedNode {
+			if bannedNodes[node.Name] {
 				continue
 			}
 
@@ -180,5 +244,8 @@ func findPlaceFor(bannedNode string, pods []*kube_api.Pod, nodes []*kube_api.Nod
 			return fmt.Errorf("failed to find place for %s", podKey)
 		}
 	}
+	for name, nodeInfo := range newNodeInfos {
+		nodeInfos[name] = nodeInfo
+	}
 	return nil
 }
@@ -17,11 +17,18 @@ limitations under the License.
 package simulator
 
 import (
+	"net/http"
+	"strings"
 	"testing"
 
 	. "k8s.io/contrib/cluster-autoscaler/utils/test"
 
 	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/apis/policy"
+	"k8s.io/kubernetes/pkg/controller"
+	"k8s.io/kubernetes/pkg/kubelet/types"
+	"k8s.io/kubernetes/pkg/runtime"
 	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
 
 	"github.com/stretchr/testify/assert"
@@ -59,7 +66,7 @@ func TestFindPlaceAllOk(t *testing.T) {
 	nodeInfos["n2"].SetNode(node2)
 
 	err := findPlaceFor(
-		"x",
+		map[string]bool{"x": true},
 		[]*kube_api.Pod{new1, new2},
 		[]*kube_api.Node{node1, node2},
 		nodeInfos, NewTestPredicateChecker())
@@ -82,7 +89,7 @@ func TestFindPlaceAllBas(t *testing.T) {
 	nodeInfos["n2"].SetNode(node2)
 
 	err := findPlaceFor(
-		"x",
+		map[string]bool{"x": true},
 		[]*kube_api.Pod{new1, new2, new3},
 		[]*kube_api.Node{node1, node2},
 		nodeInfos, NewTestPredicateChecker())
@@ -102,9 +109,149 @@ func TestFindNone(t *testing.T) {
 	nodeInfos["n2"].SetNode(node2)
 
 	err := findPlaceFor(
-		"x",
+		map[string]bool{"x": true},
 		[]*kube_api.Pod{},
 		[]*kube_api.Node{node1, node2},
 		nodeInfos, NewTestPredicateChecker())
 	assert.NoError(t, err)
 }
+
+func TestFindPlaceBannedNodes(t *testing.T) {
+	pod1 := BuildTestPod("p1", 700, 500000)
+	new1 := BuildTestPod("p2", 600, 500000)
+	new2 := BuildTestPod("p3", 600, 500000)
+
+	nodeInfos := map[string]*schedulercache.NodeInfo{
+		"n1": schedulercache.NewNodeInfo(pod1),
+		"n2": schedulercache.NewNodeInfo(),
+		"n3": schedulercache.NewNodeInfo(),
+	}
+	node1 := BuildTestNode("n1", 1000, 2000000)
+	node2 := BuildTestNode("n2", 1000, 2000000)
+	node3 := BuildTestNode("n3", 1000, 2000000)
+	nodeInfos["n1"].SetNode(node1)
+	nodeInfos["n2"].SetNode(node2)
+	nodeInfos["n3"].SetNode(node3)
+	nodes := []*kube_api.Node{node1, node2, node3}
+
+	err := findPlaceFor(map[string]bool{"n3": true}, []*kube_api.Pod{new1}, nodes, nodeInfos, NewTestPredicateChecker())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(nodeInfos["n2"].Pods()))
+
+	// n2 is already taken by the previously placed pod.
+	err = findPlaceFor(map[string]bool{"n3": true}, []*kube_api.Pod{new2}, nodes, nodeInfos, NewTestPredicateChecker())
+	assert.Error(t, err)
+}
+
+func TestFindEmptyNodes(t *testing.T) {
+	pod1 := BuildTestPod("p1", 300, 500000)
+	pod1.Spec.NodeName = "n1"
+	pod2 := BuildTestPod("p2", 300, 500000)
+	pod2.Spec.NodeName = "n2"
+	pod2.Annotations = map[string]string{
+		types.ConfigMirrorAnnotationKey: "",
+	}
+
+	node1 := BuildTestNode("n1", 1000, 2000000)
+	node2 := BuildTestNode("n2", 1000, 2000000)
+	node3 := BuildTestNode("n3", 1000, 2000000)
+
+	emptyNodes := FindEmptyNodesToRemove([]*kube_api.Node{node1, node2, node3}, []*kube_api.Pod{pod1, pod2})
+	assert.Equal(t, []*kube_api.Node{node2, node3}, emptyNodes)
+}
+
+func TestFindNodesToRemovePodDisruptionBudget(t *testing.T) {
+	replicaSetRef := "{\"kind\":\"SerializedReference\",\"apiVersion\":\"v1\",\"reference\":{\"kind\":\"ReplicaSet\"}}"
+	nodes := make([]*kube_api.Node, 0)
+	pods := make([]*kube_api.Pod, 0)
+	for _, name := range []string{"n1", "n2", "n3"} {
+		pod := BuildTestPod("p-"+name, 100, 100000)
+		pod.Spec.NodeName = name
+		pod.Labels = map[string]string{"app": "quorum"}
+		pod.Annotations = map[string]string{controller.CreatedByAnnotation: replicaSetRef}
+		pods = append(pods, pod)
+		nodes = append(nodes, BuildTestNode(name, 1000, 2000000))
+	}
+	bigPod := BuildTestPod("p-big", 100, 100000)
+	bigPod.Spec.NodeName = "big"
+	pods = append(pods, bigPod)
+	big := BuildTestNode("big", 10000, 20000000)
+	allNodes := append([]*kube_api.Node{big}, nodes...)
+
+	pdb := &policy.PodDisruptionBudget{
+		ObjectMeta: kube_api.ObjectMeta{Namespace: "default", Name: "quorum"},
+		Spec: policy.PodDisruptionBudgetSpec{
+			Selector: &unversioned.LabelSelector{MatchLabels: map[string]string{"app": "quorum"}},
+		},
+		Status: policy.PodDisruptionBudgetStatus{
+			PodDisruptionAllowed: true,
+			CurrentHealthy:       3,
+			DesiredHealthy:       2,
+		},
+	}
+
+	// Without budgets all three nodes can go.
+	toRemove, err := FindNodesToRemove(nodes, allNodes, pods, []*policy.PodDisruptionBudget{},
+		nil, NewTestPredicateChecker(), len(nodes), true)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(toRemove))
+
+	// The budget allows only one disruption in total.
+	toRemove, err = FindNodesToRemove(nodes, allNodes, pods, []*policy.PodDisruptionBudget{pdb},
+		nil, NewTestPredicateChecker(), len(nodes), true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(toRemove))
+	assert.Equal(t, "n1", toRemove[0].Node.Name)
+
+	// Budgets from other namespaces don't matter.
+	pdb.Namespace = "other"
+	toRemove, err = FindNodesToRemove(nodes, allNodes, pods, []*policy.PodDisruptionBudget{pdb},
+		nil, NewTestPredicateChecker(), len(nodes), true)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(toRemove))
+
+	// No disruptions allowed.
+	pdb.Namespace = "default"
+	pdb.Status.PodDisruptionAllowed = false
+	toRemove, err = FindNodesToRemove(nodes, allNodes, pods, []*policy.PodDisruptionBudget{pdb},
+		nil, NewTestPredicateChecker(), len(nodes), true)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(toRemove))
+}
+
+func TestFindNodesToRemoveMovedPods(t *testing.T) {
+	pods := make([]*kube_api.Pod, 0)
+	for name, cpu := range map[string]int64{"a": 500, "b": 400, "c": 550} {
+		pod := BuildTestPod("p-"+name, cpu, 100000)
+		pod.Spec.NodeName = name
+		pod.Annotations = map[string]string{SafeToEvictAnnotation: "true"}
+		pods = append(pods, pod)
+	}
+	a := BuildTestNode("a", 1000, 2000000)
+	b := BuildTestNode("b", 1000, 2000000)
+	c := BuildTestNode("c", 1000, 2000000)
+	allNodes := []*kube_api.Node{c, a, b}
+
+	client, server := NewTestKubeClient(func(req *http.Request) runtime.Object {
+		nodeName := strings.TrimPrefix(req.URL.Query().Get("fieldSelector"), "spec.nodeName=")
+		list := &kube_api.PodList{}
+		for _, pod := range pods {
+			if pod.Spec.NodeName == nodeName {
+				list.Items = append(list.Items, *pod)
+			}
+		}
+		return list
+	})
+	defer server.Close()
+
+	// The pod of a only fits on b, so b can't be removed together with a, even though
+	// its own pod would fit on c.
+	for _, fastCheck := range []bool{true, false} {
+		toRemove, err := FindNodesToRemove([]*kube_api.Node{a, b}, allNodes, pods, []*policy.PodDisruptionBudget{},
+			client, NewTestPredicateChecker(), 2, fastCheck)
+		assert.NoError(t, err)
+		if assert.Equal(t, 1, len(toRemove), "fast check: %v", fastCheck) {
+			assert.Equal(t, "a", toRemove[0].Node.Name)
+		}
+	}
+}
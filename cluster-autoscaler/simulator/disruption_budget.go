@@ -0,0 +1,94 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+
+	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/apis/policy"
+	"k8s.io/kubernetes/pkg/labels"
+
+	"github.com/golang/glog"
+)
+
+// disruptionBudget is a PodDisruptionBudget together with the number of pods that can still
+// be evicted without violating it.
+type disruptionBudget struct {
+	pdb      *policy.PodDisruptionBudget
+	selector labels.Selector
+	allowed  int
+}
+
+// disruptionBudgets tracks how many evictions are still allowed by each PodDisruptionBudget
+// over a series of simulated node removals.
+type disruptionBudgets struct {
+	budgets []*disruptionBudget
+}
+
+func newDisruptionBudgets(pdbs []*policy.PodDisruptionBudget) *disruptionBudgets {
+	result := &disruptionBudgets{budgets: make([]*disruptionBudget, 0, len(pdbs))}
+	for _, pdb := range pdbs {
+		selector, err := unversioned.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			glog.Warningf("Failed to parse selector of PodDisruptionBudget %s/%s: %v", pdb.Namespace, pdb.Name, err)
+			continue
+		}
+		allowed := 0
+		if pdb.Status.PodDisruptionAllowed {
+			allowed = int(pdb.Status.CurrentHealthy - pdb.Status.DesiredHealthy)
+		}
+		result.budgets = append(result.budgets, &disruptionBudget{
+			pdb:      pdb,
+			selector: selector,
+			allowed:  allowed,
+		})
+	}
+	return result
+}
+
+// checkEviction returns an error if evicting all the given pods, on top of the evictions
+// already recorded with recordEviction, would violate any of the budgets.
+func (d *disruptionBudgets) checkEviction(pods []*kube_api.Pod) error {
+	for _, budget := range d.budgets {
+		count := budget.matching(pods)
+		if count > 0 && count > budget.allowed {
+			return fmt.Errorf("evicting %d pods would violate PodDisruptionBudget %s/%s (%d disruptions allowed)",
+				count, budget.pdb.Namespace, budget.pdb.Name, budget.allowed)
+		}
+	}
+	return nil
+}
+
+// recordEviction lowers the budgets by the given pods.
+func (d *disruptionBudgets) recordEviction(pods []*kube_api.Pod) {
+	for _, budget := range d.budgets {
+		budget.allowed -= budget.matching(pods)
+	}
+}
+
+// matching returns the number of pods covered by the budget.
+func (budget *disruptionBudget) matching(pods []*kube_api.Pod) int {
+	count := 0
+	for _, pod := range pods {
+		if pod.Namespace == budget.pdb.Namespace && budget.selector.Matches(labels.Set(pod.Labels)) {
+			count++
+		}
+	}
+	return count
+}
@@ -20,44 +20,98 @@ import (
 	"fmt"
 
 	"k8s.io/kubernetes/pkg/api"
+	kube_errors "k8s.io/kubernetes/pkg/api/errors"
+	kube_client "k8s.io/kubernetes/pkg/client/unversioned"
 	"k8s.io/kubernetes/pkg/controller"
+	"k8s.io/kubernetes/pkg/fields"
 	"k8s.io/kubernetes/pkg/kubelet/types"
 	"k8s.io/kubernetes/pkg/runtime"
 	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// SafeToEvictAnnotation controls whether cluster autoscaler may evict the annotated pod.
+	// "false" prevents the removal of the node the pod runs on. "true" allows evicting the pod
+	// even if it is not replicated, runs in kube-system or uses local storage.
+	SafeToEvictAnnotation = "cluster-autoscaler.kubernetes.io/safe-to-evict"
 )
 
 // FastGetPodsToMove returns a list of pods that should be moved elsewhere if the node
 // is drained. Raises error if there is an unreplicated pod and force option was not specified.
-// Based on kubectl drain code. It makes an assumption that RC, DS, Jobs and RS were deleted
-// along with their pods (no abandoned pods with dangling created-by annotation). Usefull for fast
-// checks.
+// Based on kubectl drain code. It makes an assumption that RC, DS, Jobs, RS and PetSets were deleted
+// along with their pods (no abandoned pods with dangling owner references). Usefull for fast
+// checks. Pods annotated with SafeToEvictAnnotation are handled according to its value.
 func FastGetPodsToMove(nodeInfo *schedulercache.NodeInfo, force bool,
 	skipNodesWithSystemPods bool, skipNodesWithLocalStorage bool, decoder runtime.Decoder) ([]*api.Pod, error) {
+	return getPodsForDeletionOnNodeDrain(nodeInfo.Pods(), decoder, force, skipNodesWithSystemPods,
+		skipNodesWithLocalStorage, false, nil)
+}
+
+// DetailedGetPodsForMove returns a list of pods that should be moved elsewhere if the node
+// is drained. Unlike FastGetPodsToMove it fetches the current pods of the node from the api server
+// and verifies that the controllers of the pods still exist. Pods whose controller is gone are
+// treated as unreplicated.
+func DetailedGetPodsForMove(nodeName string, force bool, skipNodesWithSystemPods bool,
+	skipNodesWithLocalStorage bool, client *kube_client.Client, decoder runtime.Decoder) ([]*api.Pod, error) {
+	podList, err := client.Pods(api.NamespaceAll).List(api.ListOptions{
+		FieldSelector: fields.SelectorFromSet(fields.Set{"spec.nodeName": nodeName})})
+	if err != nil {
+		return []*api.Pod{}, err
+	}
+	pods := make([]*api.Pod, 0, len(podList.Items))
+	for i := range podList.Items {
+		pods = append(pods, &podList.Items[i])
+	}
+	return getPodsForDeletionOnNodeDrain(pods, decoder, force, skipNodesWithSystemPods,
+		skipNodesWithLocalStorage, true, client)
+}
+
+func getPodsForDeletionOnNodeDrain(podList []*api.Pod, decoder runtime.Decoder, force bool,
+	skipNodesWithSystemPods bool, skipNodesWithLocalStorage bool, checkReferences bool,
+	client *kube_client.Client) ([]*api.Pod, error) {
 	pods := make([]*api.Pod, 0)
 	unreplicatedPodNames := []string{}
-	for _, pod := range nodeInfo.Pods() {
+	for _, pod := range podList {
 		_, found := pod.ObjectMeta.Annotations[types.ConfigMirrorAnnotationKey]
 		if found {
 			// Skip mirror pod
 			continue
 		}
+		if isEvictionBlocked(pod) {
+			return []*api.Pod{}, fmt.Errorf("pod %s/%s is annotated with %s=false", pod.Namespace, pod.Name, SafeToEvictAnnotation)
+		}
+		if isSafeToEvict(pod) {
+			pods = append(pods, pod)
+			continue
+		}
 		replicated := false
 		daemonsetPod := false
 
-		creatorRef, found := pod.ObjectMeta.Annotations[controller.CreatedByAnnotation]
-		if found {
-			var sr api.SerializedReference
-			if err := runtime.DecodeInto(decoder, []byte(creatorRef), &sr); err != nil {
-				return []*api.Pod{}, err
-			}
-			if sr.Reference.Kind == "ReplicationController" {
+		controllerRef, err := GetControllerRef(pod, decoder)
+		if err != nil {
+			return []*api.Pod{}, err
+		}
+		if controllerRef != nil {
+			switch controllerRef.Kind {
+			case "ReplicationController", "Job", "ReplicaSet", "PetSet", "StatefulSet":
 				replicated = true
-			} else if sr.Reference.Kind == "DaemonSet" {
+			case "DaemonSet":
 				daemonsetPod = true
-			} else if sr.Reference.Kind == "Job" {
-				replicated = true
-			} else if sr.Reference.Kind == "ReplicaSet" {
-				replicated = true
+			}
+			if checkReferences && (replicated || daemonsetPod) {
+				exists, err := controllerExists(client, controllerRef)
+				if err != nil {
+					return []*api.Pod{}, fmt.Errorf("failed to check %s %s/%s of pod %s: %v", controllerRef.Kind,
+						controllerRef.Namespace, controllerRef.Name, pod.Name, err)
+				}
+				if !exists {
+					glog.V(4).Infof("%s %s/%s of pod %s no longer exists", controllerRef.Kind,
+						controllerRef.Namespace, controllerRef.Name, pod.Name)
+					replicated = false
+					daemonsetPod = false
+				}
 			}
 		}
 
@@ -87,6 +141,93 @@ func FastGetPodsToMove(nodeInfo *schedulercache.NodeInfo, force bool,
 	return pods, nil
 }
 
+// GetControllerRef returns a reference to the controller of the pod. Owner references take
+// precedence over the legacy created-by annotation. Returns nil if the pod has no controller.
+func GetControllerRef(pod *api.Pod, decoder runtime.Decoder) (*api.ObjectReference, error) {
+	if len(pod.OwnerReferences) > 0 {
+		// Owner references don't say which owner is the controller, so prefer the
+		// first one of a known controller kind.
+		owner := pod.OwnerReferences[0]
+		for _, ref := range pod.OwnerReferences {
+			if isControllerKind(ref.Kind) {
+				owner = ref
+				break
+			}
+		}
+		return &api.ObjectReference{
+			Kind:       owner.Kind,
+			APIVersion: owner.APIVersion,
+			Namespace:  pod.Namespace,
+			Name:       owner.Name,
+			UID:        owner.UID,
+		}, nil
+	}
+	creatorRef, found := pod.ObjectMeta.Annotations[controller.CreatedByAnnotation]
+	if !found {
+		return nil, nil
+	}
+	var sr api.SerializedReference
+	if err := runtime.DecodeInto(decoder, []byte(creatorRef), &sr); err != nil {
+		return nil, err
+	}
+	return &sr.Reference, nil
+}
+
+func isControllerKind(kind string) bool {
+	switch kind {
+	case "ReplicationController", "Job", "ReplicaSet", "PetSet", "StatefulSet", "DaemonSet":
+		return true
+	}
+	return false
+}
+
+// controllerExists checks whether the referenced controller is still present in the cluster.
+func controllerExists(client *kube_client.Client, ref *api.ObjectReference) (bool, error) {
+	var err error
+	switch ref.Kind {
+	case "ReplicationController":
+		_, err = client.ReplicationControllers(ref.Namespace).Get(ref.Name)
+	case "DaemonSet":
+		_, err = client.ExtensionsClient.DaemonSets(ref.Namespace).Get(ref.Name)
+	case "Job":
+		_, err = client.ExtensionsClient.Jobs(ref.Namespace).Get(ref.Name)
+	case "ReplicaSet":
+		_, err = client.ExtensionsClient.ReplicaSets(ref.Namespace).Get(ref.Name)
+	case "PetSet", "StatefulSet":
+		// StatefulSet is the new name of PetSet. The vendored client only knows the
+		// PetSet api, so both are looked up there.
+		_, err = client.AppsClient.PetSets(ref.Namespace).Get(ref.Name)
+	default:
+		return false, fmt.Errorf("unsupported controller kind %s", ref.Kind)
+	}
+	if err != nil {
+		if kube_errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// FindBlockingPod returns the first of the given pods annotated as not safe to evict, or nil
+// if there is no such pod.
+func FindBlockingPod(pods []*api.Pod) *api.Pod {
+	for _, pod := range pods {
+		if isEvictionBlocked(pod) {
+			return pod
+		}
+	}
+	return nil
+}
+
+func isEvictionBlocked(pod *api.Pod) bool {
+	return pod.Annotations[SafeToEvictAnnotation] == "false"
+}
+
+func isSafeToEvict(pod *api.Pod) bool {
+	return pod.Annotations[SafeToEvictAnnotation] == "true"
+}
+
 func hasLocalStorage(pod *api.Pod) bool {
 	for _, volume := range pod.Spec.Volumes {
 		if isLocalVolume(&volume) {
@@ -17,10 +17,16 @@ limitations under the License.
 package simulator
 
 import (
+	"net/http"
 	"testing"
 
+	. "k8s.io/contrib/cluster-autoscaler/utils/test"
+
 	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/apis/apps"
+	"k8s.io/kubernetes/pkg/apis/extensions"
 	"k8s.io/kubernetes/pkg/kubelet/types"
+	"k8s.io/kubernetes/pkg/runtime"
 	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
 
 	"github.com/stretchr/testify/assert"
@@ -142,3 +148,160 @@ func TestFastGetPodsToMove(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(r7))
 }
+
+func TestFastGetPodsToMoveSafeToEvict(t *testing.T) {
+	// Unreplicated pod with local storage marked as safe to evict.
+	pod1 := &kube_api.Pod{
+		ObjectMeta: kube_api.ObjectMeta{
+			Name:      "pod1",
+			Namespace: "ns",
+			Annotations: map[string]string{
+				SafeToEvictAnnotation: "true",
+			},
+		},
+		Spec: kube_api.PodSpec{
+			Volumes: []kube_api.Volume{
+				{Name: "cache", VolumeSource: kube_api.VolumeSource{EmptyDir: &kube_api.EmptyDirVolumeSource{}}},
+			},
+		},
+	}
+	r1, err := FastGetPodsToMove(schedulercache.NewNodeInfo(pod1), false, true, true, kube_api.Codecs.UniversalDecoder())
+	assert.NoError(t, err)
+	assert.Equal(t, []*kube_api.Pod{pod1}, r1)
+
+	// Replicated pod marked as not safe to evict.
+	pod2 := &kube_api.Pod{
+		ObjectMeta: kube_api.ObjectMeta{
+			Name:      "pod2",
+			Namespace: "ns",
+			Annotations: map[string]string{
+				"kubernetes.io/created-by": "{\"kind\":\"SerializedReference\",\"apiVersion\":\"v1\",\"reference\":{\"kind\":\"ReplicaSet\"}}",
+				SafeToEvictAnnotation:      "false",
+			},
+		},
+	}
+	_, err = FastGetPodsToMove(schedulercache.NewNodeInfo(pod2), true, false, false, kube_api.Codecs.UniversalDecoder())
+	assert.Error(t, err)
+	assert.Equal(t, pod2, FindBlockingPod([]*kube_api.Pod{pod1, pod2}))
+	assert.Nil(t, FindBlockingPod([]*kube_api.Pod{pod1}))
+}
+
+func TestGetPodsForDeletionOnNodeDrain(t *testing.T) {
+	rsAnnotation := map[string]string{
+		"kubernetes.io/created-by": "{\"kind\":\"SerializedReference\",\"apiVersion\":\"v1\",\"reference\":{\"kind\":\"ReplicaSet\",\"namespace\":\"ns\",\"name\":\"rs\"}}",
+	}
+	buildPod := func(name string, annotations map[string]string, owners ...kube_api.OwnerReference) *kube_api.Pod {
+		return &kube_api.Pod{
+			ObjectMeta: kube_api.ObjectMeta{
+				Name:            name,
+				Namespace:       "ns",
+				Annotations:     annotations,
+				OwnerReferences: owners,
+			},
+		}
+	}
+
+	rsPod := buildPod("rs-pod", nil, kube_api.OwnerReference{Kind: "ReplicaSet", Name: "rs"})
+	orphanedRsPod := buildPod("orphaned-rs-pod", nil, kube_api.OwnerReference{Kind: "ReplicaSet", Name: "missing"})
+	petSetPod := buildPod("pet-0", nil, kube_api.OwnerReference{Kind: "PetSet", Name: "pets"})
+	orphanedPetSetPod := buildPod("orphaned-pet-0", nil, kube_api.OwnerReference{Kind: "PetSet", Name: "missing"})
+	statefulSetPod := buildPod("stateful-0", nil, kube_api.OwnerReference{Kind: "StatefulSet", Name: "pets"})
+	dsPod := buildPod("ds-pod", nil, kube_api.OwnerReference{Kind: "DaemonSet", Name: "ds"})
+	annotatedPod := buildPod("annotated-pod", rsAnnotation)
+	unknownOwnerPod := buildPod("unknown-owner-pod", nil, kube_api.OwnerReference{Kind: "Foo", Name: "foo"})
+	multipleOwnersPod := buildPod("multiple-owners-pod", nil, kube_api.OwnerReference{Kind: "Foo", Name: "foo"},
+		kube_api.OwnerReference{Kind: "ReplicaSet", Name: "rs"})
+	// Owner references take precedence over the annotation.
+	ownerOverAnnotationPod := buildPod("owner-over-annotation-pod", rsAnnotation, kube_api.OwnerReference{Kind: "Foo", Name: "foo"})
+
+	client, server := NewTestKubeClient(func(req *http.Request) runtime.Object {
+		switch req.URL.Path {
+		case "/apis/extensions/v1beta1/namespaces/ns/replicasets/rs":
+			return &extensions.ReplicaSet{ObjectMeta: kube_api.ObjectMeta{Namespace: "ns", Name: "rs"}}
+		case "/apis/extensions/v1beta1/namespaces/ns/daemonsets/ds":
+			return &extensions.DaemonSet{ObjectMeta: kube_api.ObjectMeta{Namespace: "ns", Name: "ds"}}
+		case "/apis/apps/v1alpha1/namespaces/ns/petsets/pets":
+			return &apps.PetSet{ObjectMeta: kube_api.ObjectMeta{Namespace: "ns", Name: "pets"}}
+		}
+		return nil
+	})
+	defer server.Close()
+
+	tests := []struct {
+		description     string
+		pods            []*kube_api.Pod
+		checkReferences bool
+		expectError     bool
+		expectPods      []*kube_api.Pod
+	}{
+		{
+			description: "ReplicaSet owner",
+			pods:        []*kube_api.Pod{rsPod},
+			expectPods:  []*kube_api.Pod{rsPod},
+		},
+		{
+			description: "created-by annotation fallback",
+			pods:        []*kube_api.Pod{annotatedPod},
+			expectPods:  []*kube_api.Pod{annotatedPod},
+		},
+		{
+			description: "PetSet and StatefulSet owners",
+			pods:        []*kube_api.Pod{petSetPod, statefulSetPod},
+			expectPods:  []*kube_api.Pod{petSetPod, statefulSetPod},
+		},
+		{
+			description: "DaemonSet owner",
+			pods:        []*kube_api.Pod{dsPod, rsPod},
+			expectPods:  []*kube_api.Pod{rsPod},
+		},
+		{
+			description: "unknown owner kind",
+			pods:        []*kube_api.Pod{unknownOwnerPod},
+			expectError: true,
+		},
+		{
+			description: "controller among multiple owners",
+			pods:        []*kube_api.Pod{multipleOwnersPod},
+			expectPods:  []*kube_api.Pod{multipleOwnersPod},
+		},
+		{
+			description: "owner references before annotation",
+			pods:        []*kube_api.Pod{ownerOverAnnotationPod},
+			expectError: true,
+		},
+		{
+			description:     "existing controllers",
+			pods:            []*kube_api.Pod{rsPod, petSetPod, statefulSetPod, dsPod, annotatedPod},
+			checkReferences: true,
+			expectPods:      []*kube_api.Pod{rsPod, petSetPod, statefulSetPod, annotatedPod},
+		},
+		{
+			description:     "deleted ReplicaSet",
+			pods:            []*kube_api.Pod{orphanedRsPod},
+			checkReferences: true,
+			expectError:     true,
+		},
+		{
+			description:     "deleted PetSet",
+			pods:            []*kube_api.Pod{orphanedPetSetPod},
+			checkReferences: true,
+			expectError:     true,
+		},
+		{
+			description: "deleted controllers are not checked in fast mode",
+			pods:        []*kube_api.Pod{orphanedRsPod, orphanedPetSetPod},
+			expectPods:  []*kube_api.Pod{orphanedRsPod, orphanedPetSetPod},
+		},
+	}
+
+	for _, test := range tests {
+		pods, err := getPodsForDeletionOnNodeDrain(test.pods, kube_api.Codecs.UniversalDecoder(), false, true, true,
+			test.checkReferences, client)
+		if test.expectError {
+			assert.Error(t, err, test.description)
+		} else {
+			assert.NoError(t, err, test.description)
+			assert.Equal(t, test.expectPods, pods, test.description)
+		}
+	}
+}
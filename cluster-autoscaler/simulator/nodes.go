@@ -21,6 +21,7 @@ import (
 	kube_client "k8s.io/kubernetes/pkg/client/unversioned"
 	"k8s.io/kubernetes/pkg/fields"
 	cmd "k8s.io/kubernetes/pkg/kubectl/cmd"
+	"k8s.io/kubernetes/pkg/kubelet/types"
 	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
 )
 
@@ -54,9 +55,59 @@ func GetRequiredPodsForNode(nodename string, client *kube_client.Client) ([]*kub
 	return podsOnNewNode, nil
 }
 
+// RequiredPodsLister lists the pods that would appear on a node if the node was just created.
+type RequiredPodsLister interface {
+	RequiredPodsForNode(nodeName string) ([]*kube_api.Pod, error)
+}
+
+type clientRequiredPodsLister struct {
+	client *kube_client.Client
+}
+
+// NewClientRequiredPodsLister returns a RequiredPodsLister that fetches the pods from the api server.
+func NewClientRequiredPodsLister(client *kube_client.Client) RequiredPodsLister {
+	return &clientRequiredPodsLister{client: client}
+}
+
+func (lister *clientRequiredPodsLister) RequiredPodsForNode(nodeName string) ([]*kube_api.Pod, error) {
+	return GetRequiredPodsForNode(nodeName, lister.client)
+}
+
+type staticRequiredPodsLister struct {
+	pods []*kube_api.Pod
+}
+
+// NewStaticRequiredPodsLister returns a RequiredPodsLister that picks the mirror and DaemonSet pods
+// of the node from the given pods.
+func NewStaticRequiredPodsLister(pods []*kube_api.Pod) RequiredPodsLister {
+	return &staticRequiredPodsLister{pods: pods}
+}
+
+func (lister *staticRequiredPodsLister) RequiredPodsForNode(nodeName string) ([]*kube_api.Pod, error) {
+	decoder := kube_api.Codecs.UniversalDecoder()
+	result := make([]*kube_api.Pod, 0)
+	for _, pod := range lister.pods {
+		if pod.Spec.NodeName != nodeName {
+			continue
+		}
+		if _, found := pod.Annotations[types.ConfigMirrorAnnotationKey]; found {
+			result = append(result, pod)
+			continue
+		}
+		controllerRef, err := GetControllerRef(pod, decoder)
+		if err != nil {
+			return []*kube_api.Pod{}, err
+		}
+		if controllerRef != nil && controllerRef.Kind == "DaemonSet" {
+			result = append(result, pod)
+		}
+	}
+	return result, nil
+}
+
 // BuildNodeInfoForNode build a NodeInfo structure for the given node as if the node was just created.
-func BuildNodeInfoForNode(node *kube_api.Node, client *kube_client.Client) (*schedulercache.NodeInfo, error) {
-	requiredPods, err := GetRequiredPodsForNode(node.Name, client)
+func BuildNodeInfoForNode(node *kube_api.Node, lister RequiredPodsLister) (*schedulercache.NodeInfo, error) {
+	requiredPods, err := lister.RequiredPodsForNode(node.Name)
 	if err != nil {
 		return nil, err
 	}
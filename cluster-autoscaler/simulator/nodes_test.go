@@ -31,6 +31,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"k8s.io/kubernetes/pkg/client/unversioned/fake"
+	"k8s.io/kubernetes/pkg/controller"
 	"k8s.io/kubernetes/pkg/kubelet/types"
 	"k8s.io/kubernetes/pkg/runtime"
 )
@@ -102,3 +103,29 @@ func TestRequiredPodsForNode(t *testing.T) {
 func objBody(codec runtime.Codec, obj runtime.Object) io.ReadCloser {
 	return ioutil.NopCloser(bytes.NewReader([]byte(runtime.EncodeOrDie(codec, obj))))
 }
+
+func TestStaticRequiredPodsLister(t *testing.T) {
+	daemonSetRef := "{\"kind\":\"SerializedReference\",\"apiVersion\":\"v1\",\"reference\":{\"kind\":\"DaemonSet\"}}"
+	replicaSetRef := "{\"kind\":\"SerializedReference\",\"apiVersion\":\"v1\",\"reference\":{\"kind\":\"ReplicaSet\"}}"
+	mirror := &kube_api.Pod{
+		ObjectMeta: kube_api.ObjectMeta{Name: "mirror", Annotations: map[string]string{types.ConfigMirrorAnnotationKey: ""}},
+		Spec:       kube_api.PodSpec{NodeName: "node1"},
+	}
+	daemon := &kube_api.Pod{
+		ObjectMeta: kube_api.ObjectMeta{Name: "daemon", Annotations: map[string]string{controller.CreatedByAnnotation: daemonSetRef}},
+		Spec:       kube_api.PodSpec{NodeName: "node1"},
+	}
+	replicated := &kube_api.Pod{
+		ObjectMeta: kube_api.ObjectMeta{Name: "replicated", Annotations: map[string]string{controller.CreatedByAnnotation: replicaSetRef}},
+		Spec:       kube_api.PodSpec{NodeName: "node1"},
+	}
+	otherDaemon := &kube_api.Pod{
+		ObjectMeta: kube_api.ObjectMeta{Name: "other-daemon", Annotations: map[string]string{controller.CreatedByAnnotation: daemonSetRef}},
+		Spec:       kube_api.PodSpec{NodeName: "node2"},
+	}
+
+	lister := NewStaticRequiredPodsLister([]*kube_api.Pod{mirror, daemon, replicated, otherDaemon})
+	pods, err := lister.RequiredPodsForNode("node1")
+	assert.NoError(t, err)
+	assert.Equal(t, []*kube_api.Pod{mirror, daemon}, pods)
+}
@@ -18,6 +18,8 @@ package simulator
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	kube_api "k8s.io/kubernetes/pkg/api"
 	kube_client "k8s.io/kubernetes/pkg/client/unversioned"
@@ -32,6 +34,32 @@ import (
 // PredicateChecker checks whether all required predicates are matched for given Pod and Node
 type PredicateChecker struct {
 	predicates map[string]algorithm.FitPredicate
+	// Names of the predicates in the order in which they are checked.
+	predicateNames []string
+}
+
+// newPredicateChecker builds PredicateChecker for the given predicates. GeneralPredicates stops at
+// its first failure, so it is replaced with the predicates it consists of to report all of them.
+func newPredicateChecker(fitPredicates map[string]algorithm.FitPredicate) *PredicateChecker {
+	result := &PredicateChecker{
+		predicates:     make(map[string]algorithm.FitPredicate),
+		predicateNames: make([]string, 0, len(fitPredicates)),
+	}
+	for name, predicate := range fitPredicates {
+		if name == "GeneralPredicates" {
+			result.predicates["PodFitsResources"] = predicates.PodFitsResources
+			result.predicates["HostName"] = predicates.PodFitsHost
+			result.predicates["PodFitsHostPorts"] = predicates.PodFitsHostPorts
+			result.predicates["MatchNodeSelector"] = predicates.PodSelectorMatches
+			continue
+		}
+		result.predicates[name] = predicate
+	}
+	for name := range result.predicates {
+		result.predicateNames = append(result.predicateNames, name)
+	}
+	sort.Strings(result.predicateNames)
+	return result
 }
 
 // NewPredicateChecker builds PredicateChecker.
@@ -46,18 +74,14 @@ func NewPredicateChecker(kubeClient *kube_client.Client) (*PredicateChecker, err
 		return nil, err
 	}
 	schedulerConfigFactory.Run()
-	return &PredicateChecker{
-		predicates: predicates,
-	}, nil
+	return newPredicateChecker(predicates), nil
 }
 
 // NewTestPredicateChecker builds test version of PredicateChecker.
 func NewTestPredicateChecker() *PredicateChecker {
-	return &PredicateChecker{
-		predicates: map[string]algorithm.FitPredicate{
-			"default": predicates.GeneralPredicates,
-		},
-	}
+	return newPredicateChecker(map[string]algorithm.FitPredicate{
+		"GeneralPredicates": predicates.GeneralPredicates,
+	})
 }
 
 // FitsAny checks if the given pod can be place on any of the given nodes.
@@ -70,20 +94,75 @@ func (p *PredicateChecker) FitsAny(pod *kube_api.Pod, nodeInfos map[string]*sche
 	return "", fmt.Errorf("cannot put pod %s on any node", pod.Name)
 }
 
-// CheckPredicates checks if the given pod can be placed on the given node.
+// PredicateError is returned by CheckPredicates and CheckAllPredicates if the pod can't be placed
+// on the node.
+type PredicateError struct {
+	message string
+	reasons []string
+}
+
+// Error returns the full description of the failure.
+func (e *PredicateError) Error() string {
+	return e.message
+}
+
+// Reason returns a short description of the failed predicates, like "Insufficient memory"
+// or "Insufficient memory, MatchNodeSelector".
+func (e *PredicateError) Reason() string {
+	return strings.Join(e.reasons, ", ")
+}
+
+// CheckPredicates checks if the given pod can be placed on the given node. If it can't, the
+// returned error is a *PredicateError describing the first failed predicate.
 func (p *PredicateChecker) CheckPredicates(pod *kube_api.Pod, nodeInfo *schedulercache.NodeInfo) error {
-	for _, predicate := range p.predicates {
-		match, err := predicate(pod, nodeInfo)
-		nodename := "unknown"
-		if nodeInfo.Node() != nil {
-			nodename = nodeInfo.Node().Name
-		}
+	return p.checkPredicates(pod, nodeInfo, false)
+}
+
+// CheckAllPredicates is CheckPredicates that doesn't stop at the first failed predicate, so the
+// returned *PredicateError describes all of them.
+func (p *PredicateChecker) CheckAllPredicates(pod *kube_api.Pod, nodeInfo *schedulercache.NodeInfo) error {
+	return p.checkPredicates(pod, nodeInfo, true)
+}
+
+func (p *PredicateChecker) checkPredicates(pod *kube_api.Pod, nodeInfo *schedulercache.NodeInfo, all bool) error {
+	nodename := "unknown"
+	if nodeInfo.Node() != nil {
+		nodename = nodeInfo.Node().Name
+	}
+	messages := make([]string, 0)
+	reasons := make([]string, 0)
+	for _, name := range p.predicateNames {
+		match, err := p.predicates[name](pod, nodeInfo)
 		if err != nil {
-			return fmt.Errorf("cannot put %s on %s due to %v", pod.Name, nodename, err)
+			messages = append(messages, err.Error())
+			reasons = append(reasons, predicateFailureReason(name, err))
+		} else if !match {
+			messages = append(messages, name)
+			reasons = append(reasons, name)
+		} else {
+			continue
 		}
-		if !match {
-			return fmt.Errorf("cannot put %s on %s", pod.Name, nodename)
+		if !all {
+			break
 		}
 	}
-	return nil
+	if len(reasons) == 0 {
+		return nil
+	}
+	return &PredicateError{
+		message: fmt.Sprintf("cannot put %s on %s due to %s", pod.Name, nodename, strings.Join(messages, ", ")),
+		reasons: reasons,
+	}
+}
+
+// predicateFailureReason builds a short description of the error returned by the named predicate.
+func predicateFailureReason(name string, err error) string {
+	switch predicateErr := err.(type) {
+	case *predicates.InsufficientResourceError:
+		return "Insufficient " + strings.ToLower(predicateErr.ResourceName)
+	case *predicates.PredicateFailureError:
+		return predicateErr.PredicateName
+	default:
+		return fmt.Sprintf("%s: %v", name, err)
+	}
 }
@@ -57,3 +57,34 @@ func TestPredicates(t *testing.T) {
 	assert.NoError(t, predicateChecker.CheckPredicates(p4, ni2))
 	assert.Error(t, predicateChecker.CheckPredicates(p3, ni2))
 }
+
+func TestPredicateErrorReason(t *testing.T) {
+	p1 := BuildTestPod("p1", 2000, 0)
+	p2 := BuildTestPod("p2", 100, 0)
+	p2.Spec.NodeSelector = map[string]string{"gpu": "true"}
+
+	ni := schedulercache.NewNodeInfo()
+	ni.SetNode(BuildTestNode("n1", 1000, 2000000))
+
+	predicateChecker := NewTestPredicateChecker()
+
+	err := predicateChecker.CheckPredicates(p1, ni)
+	assert.Error(t, err)
+	assert.Equal(t, "Insufficient cpu", err.(*PredicateError).Reason())
+
+	err = predicateChecker.CheckPredicates(p2, ni)
+	assert.Error(t, err)
+	assert.Equal(t, "MatchNodeSelector", err.(*PredicateError).Reason())
+
+	// All failed predicates are reported, in the order of their names.
+	p3 := BuildTestPod("p3", 2000, 0)
+	p3.Spec.NodeSelector = map[string]string{"gpu": "true"}
+	for i := 0; i < 10; i++ {
+		err = predicateChecker.CheckAllPredicates(p3, ni)
+		assert.Error(t, err)
+		assert.Equal(t, "MatchNodeSelector, Insufficient cpu", err.(*PredicateError).Reason())
+	}
+	err = predicateChecker.CheckPredicates(p3, ni)
+	assert.Error(t, err)
+	assert.Equal(t, "MatchNodeSelector", err.(*PredicateError).Reason())
+}
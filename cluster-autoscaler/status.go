@@ -0,0 +1,269 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/contrib/cluster-autoscaler/cloudprovider"
+
+	kube_api "k8s.io/kubernetes/pkg/api"
+	kube_errors "k8s.io/kubernetes/pkg/api/errors"
+	kube_client "k8s.io/kubernetes/pkg/client/unversioned"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// StatusConfigMapName is the name of the ConfigMap with the cluster autoscaler status.
+	StatusConfigMapName = "cluster-autoscaler-status"
+	// StatusConfigMapNamespace is the namespace of the ConfigMap with the cluster autoscaler status.
+	StatusConfigMapNamespace = "kube-system"
+	// StatusConfigMapKey is the ConfigMap data key under which the status is stored as JSON.
+	StatusConfigMapKey = "status"
+
+	// HealthStatusHealthy means that the cluster or node group can be autoscaled.
+	HealthStatusHealthy = "Healthy"
+	// HealthStatusUnhealthy means that something is wrong with the cluster or node group.
+	HealthStatusUnhealthy = "Unhealthy"
+)
+
+// ClusterStatus is the cluster autoscaler status published after each loop.
+type ClusterStatus struct {
+	// Time when the status was built.
+	Time time.Time `json:"time"`
+	// Health is HealthStatusHealthy if all node groups are healthy.
+	Health string `json:"health"`
+	// HealthMessage lists the unhealthy node groups.
+	HealthMessage string `json:"healthMessage,omitempty"`
+	// NodeGroups contains the status of each node group.
+	NodeGroups []NodeGroupStatus `json:"nodeGroups"`
+}
+
+// NodeGroupStatus is the status of a single node group.
+type NodeGroupStatus struct {
+	// Id of the node group.
+	Id string `json:"id"`
+	// TargetSize is the size of the node group requested from the cloud provider.
+	TargetSize int `json:"targetSize"`
+	// CurrentSize is the number of nodes of the node group registered in Kubernetes.
+	CurrentSize int `json:"currentSize"`
+	// ReadyNodes is the number of registered nodes that are ready.
+	ReadyNodes int `json:"readyNodes"`
+	// MinSize of the node group.
+	MinSize int `json:"minSize"`
+	// MaxSize of the node group.
+	MaxSize int `json:"maxSize"`
+	// UnneededNodes are nodes of the node group that are candidates for scale down.
+	UnneededNodes []UnneededNodeStatus `json:"unneededNodes"`
+	// LastScaleUpTime is the last time the node group was scaled up by cluster autoscaler.
+	LastScaleUpTime *time.Time `json:"lastScaleUpTime,omitempty"`
+	// LastScaleDownTime is the last time a node was removed from the node group by cluster autoscaler.
+	LastScaleDownTime *time.Time `json:"lastScaleDownTime,omitempty"`
+	// Health is HealthStatusHealthy if all nodes requested from the cloud provider are registered and ready.
+	Health string `json:"health"`
+	// HealthMessage explains why the node group is unhealthy.
+	HealthMessage string `json:"healthMessage,omitempty"`
+}
+
+// UnneededNodeStatus describes a node that is a candidate for scale down.
+type UnneededNodeStatus struct {
+	// Name of the node.
+	Name string `json:"name"`
+	// UnneededSince is the time since which the node has been unneeded.
+	UnneededSince time.Time `json:"unneededSince"`
+	// UnneededFor is how long the node has been unneeded.
+	UnneededFor string `json:"unneededFor"`
+}
+
+// StatusTracker remembers scale up and scale down times and builds the cluster autoscaler status.
+// It also serves the last built status over http.
+type StatusTracker struct {
+	sync.Mutex
+	lastScaleUpTime   map[string]time.Time
+	lastScaleDownTime map[string]time.Time
+	status            *ClusterStatus
+}
+
+// NewStatusTracker builds new StatusTracker.
+func NewStatusTracker() *StatusTracker {
+	return &StatusTracker{
+		lastScaleUpTime:   make(map[string]time.Time),
+		lastScaleDownTime: make(map[string]time.Time),
+	}
+}
+
+// RegisterScaleUp records that the node group was scaled up at the given time.
+func (tracker *StatusTracker) RegisterScaleUp(nodeGroupId string, now time.Time) {
+	tracker.Lock()
+	defer tracker.Unlock()
+	tracker.lastScaleUpTime[nodeGroupId] = now
+}
+
+// RegisterScaleDown records that a node was removed from the node group at the given time.
+func (tracker *StatusTracker) RegisterScaleDown(nodeGroupId string, now time.Time) {
+	tracker.Lock()
+	defer tracker.Unlock()
+	tracker.lastScaleDownTime[nodeGroupId] = now
+}
+
+// UpdateStatus builds the status from all registered nodes and the current unneeded nodes,
+// remembers it and returns it.
+func (tracker *StatusTracker) UpdateStatus(cloudProvider cloudprovider.CloudProvider, allNodes []*kube_api.Node,
+	unneededNodes map[string]time.Time, now time.Time) *ClusterStatus {
+
+	groupStatuses := make(map[string]*NodeGroupStatus)
+	status := &ClusterStatus{
+		Time:       now,
+		Health:     HealthStatusHealthy,
+		NodeGroups: make([]NodeGroupStatus, 0),
+	}
+
+	tracker.Lock()
+	nodeGroups := cloudProvider.NodeGroups()
+	for _, nodeGroup := range nodeGroups {
+		groupStatus := &NodeGroupStatus{
+			Id:            nodeGroup.Id(),
+			MinSize:       nodeGroup.MinSize(),
+			MaxSize:       nodeGroup.MaxSize(),
+			UnneededNodes: make([]UnneededNodeStatus, 0),
+		}
+		if scaleUpTime, found := tracker.lastScaleUpTime[nodeGroup.Id()]; found {
+			groupStatus.LastScaleUpTime = &scaleUpTime
+		}
+		if scaleDownTime, found := tracker.lastScaleDownTime[nodeGroup.Id()]; found {
+			groupStatus.LastScaleDownTime = &scaleDownTime
+		}
+		groupStatuses[nodeGroup.Id()] = groupStatus
+	}
+	tracker.Unlock()
+
+	for _, node := range allNodes {
+		nodeGroup, err := cloudProvider.NodeGroupForNode(node)
+		if err != nil {
+			glog.V(4).Infof("Node %s not included in the status: %v", node.Name, err)
+			continue
+		}
+		groupStatus, found := groupStatuses[nodeGroup.Id()]
+		if !found {
+			continue
+		}
+		groupStatus.CurrentSize++
+		if isNodeReady(node) {
+			groupStatus.ReadyNodes++
+		}
+		if since, found := unneededNodes[node.Name]; found {
+			groupStatus.UnneededNodes = append(groupStatus.UnneededNodes, UnneededNodeStatus{
+				Name:          node.Name,
+				UnneededSince: since,
+				UnneededFor:   now.Sub(since).String(),
+			})
+		}
+	}
+
+	unhealthyGroups := make([]string, 0)
+	for _, nodeGroup := range nodeGroups {
+		groupStatus := groupStatuses[nodeGroup.Id()]
+		sort.Sort(unneededNodesByName(groupStatus.UnneededNodes))
+		groupStatus.Health = HealthStatusHealthy
+		targetSize, err := nodeGroup.TargetSize()
+		if err != nil {
+			groupStatus.Health = HealthStatusUnhealthy
+			groupStatus.HealthMessage = fmt.Sprintf("failed to get target size: %v", err)
+		} else {
+			groupStatus.TargetSize = targetSize
+			if groupStatus.CurrentSize != targetSize || groupStatus.ReadyNodes != targetSize {
+				groupStatus.Health = HealthStatusUnhealthy
+				groupStatus.HealthMessage = fmt.Sprintf("%d of %d requested nodes registered, %d ready",
+					groupStatus.CurrentSize, targetSize, groupStatus.ReadyNodes)
+			}
+		}
+		status.NodeGroups = append(status.NodeGroups, *groupStatus)
+		if groupStatus.Health != HealthStatusHealthy {
+			unhealthyGroups = append(unhealthyGroups, groupStatus.Id)
+		}
+	}
+	if len(unhealthyGroups) > 0 {
+		status.Health = HealthStatusUnhealthy
+		status.HealthMessage = fmt.Sprintf("unhealthy node groups: %s", strings.Join(unhealthyGroups, ", "))
+	}
+
+	tracker.Lock()
+	tracker.status = status
+	tracker.Unlock()
+	return status
+}
+
+// ServeHTTP writes the last built status as JSON.
+func (tracker *StatusTracker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	tracker.Lock()
+	status := tracker.status
+	tracker.Unlock()
+	if status == nil {
+		http.Error(w, "status not available yet", http.StatusServiceUnavailable)
+		return
+	}
+	body, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// WriteStatusConfigMap stores the status as JSON in the status ConfigMap, creating the ConfigMap
+// if needed.
+func WriteStatusConfigMap(kubeClient *kube_client.Client, status *ClusterStatus) error {
+	body, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	configMaps := kubeClient.ConfigMaps(StatusConfigMapNamespace)
+	configMap, err := configMaps.Get(StatusConfigMapName)
+	if err != nil {
+		if !kube_errors.IsNotFound(err) {
+			return err
+		}
+		_, err = configMaps.Create(&kube_api.ConfigMap{
+			ObjectMeta: kube_api.ObjectMeta{
+				Namespace: StatusConfigMapNamespace,
+				Name:      StatusConfigMapName,
+			},
+			Data: map[string]string{StatusConfigMapKey: string(body)},
+		})
+		return err
+	}
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[StatusConfigMapKey] = string(body)
+	_, err = configMaps.Update(configMap)
+	return err
+}
+
+type unneededNodesByName []UnneededNodeStatus
+
+func (s unneededNodesByName) Len() int           { return len(s) }
+func (s unneededNodesByName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s unneededNodesByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
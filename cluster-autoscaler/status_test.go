@@ -0,0 +1,113 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	testprovider "k8s.io/contrib/cluster-autoscaler/cloudprovider/test"
+	. "k8s.io/contrib/cluster-autoscaler/utils/test"
+
+	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/runtime"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateStatus(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	n3 := BuildTestNode("n3", 1000, 1000)
+	for _, node := range []*kube_api.Node{n1, n2, n3} {
+		node.Status.Conditions = []kube_api.NodeCondition{{Type: kube_api.NodeReady, Status: kube_api.ConditionTrue}}
+	}
+	n3.Status.Conditions[0].Status = kube_api.ConditionFalse
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 2)
+	provider.AddNodeGroup("ng2", 0, 5, 1)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng1", n2)
+	provider.AddNode("ng2", n3)
+
+	now := time.Now()
+	tracker := NewStatusTracker()
+	tracker.RegisterScaleUp("ng1", now.Add(-time.Hour))
+	status := tracker.UpdateStatus(provider, []*kube_api.Node{n1, n2, n3},
+		map[string]time.Time{"n2": now.Add(-5 * time.Minute)}, now)
+
+	assert.Equal(t, HealthStatusUnhealthy, status.Health)
+	assert.Equal(t, "unhealthy node groups: ng2", status.HealthMessage)
+	assert.Equal(t, 2, len(status.NodeGroups))
+
+	ng1 := status.NodeGroups[0]
+	assert.Equal(t, "ng1", ng1.Id)
+	assert.Equal(t, 2, ng1.TargetSize)
+	assert.Equal(t, 2, ng1.CurrentSize)
+	assert.Equal(t, 2, ng1.ReadyNodes)
+	assert.Equal(t, 1, ng1.MinSize)
+	assert.Equal(t, 10, ng1.MaxSize)
+	assert.Equal(t, HealthStatusHealthy, ng1.Health)
+	assert.Equal(t, now.Add(-time.Hour), *ng1.LastScaleUpTime)
+	assert.Nil(t, ng1.LastScaleDownTime)
+	assert.Equal(t, []UnneededNodeStatus{{Name: "n2", UnneededSince: now.Add(-5 * time.Minute), UnneededFor: "5m0s"}},
+		ng1.UnneededNodes)
+
+	ng2 := status.NodeGroups[1]
+	assert.Equal(t, 1, ng2.CurrentSize)
+	assert.Equal(t, 0, ng2.ReadyNodes)
+	assert.Equal(t, HealthStatusUnhealthy, ng2.Health)
+
+	n3.Status.Conditions[0].Status = kube_api.ConditionTrue
+	status = tracker.UpdateStatus(provider, []*kube_api.Node{n1, n2, n3}, map[string]time.Time{}, now)
+	assert.Equal(t, HealthStatusHealthy, status.Health)
+	assert.Empty(t, status.HealthMessage)
+
+	response := httptest.NewRecorder()
+	tracker.ServeHTTP(response, &http.Request{})
+	served := &ClusterStatus{}
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), served))
+	assert.Equal(t, HealthStatusHealthy, served.Health)
+}
+
+func TestWriteStatusConfigMap(t *testing.T) {
+	var stored *kube_api.ConfigMap
+	client, server := NewTestKubeClient(func(req *http.Request) runtime.Object {
+		switch req.Method + " " + req.URL.Path {
+		case "GET /api/v1/namespaces/kube-system/configmaps/cluster-autoscaler-status":
+			if stored == nil {
+				return nil
+			}
+			return stored
+		case "POST /api/v1/namespaces/kube-system/configmaps", "PUT /api/v1/namespaces/kube-system/configmaps/cluster-autoscaler-status":
+			stored = DecodeTestObject(req, &kube_api.ConfigMap{}).(*kube_api.ConfigMap)
+			return stored
+		}
+		return nil
+	})
+	defer server.Close()
+
+	assert.NoError(t, WriteStatusConfigMap(client, &ClusterStatus{Health: HealthStatusHealthy}))
+	assert.Contains(t, stored.Data[StatusConfigMapKey], HealthStatusHealthy)
+
+	assert.NoError(t, WriteStatusConfigMap(client, &ClusterStatus{Health: HealthStatusUnhealthy}))
+	assert.Contains(t, stored.Data[StatusConfigMapKey], HealthStatusUnhealthy)
+}
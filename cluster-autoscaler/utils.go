@@ -20,12 +20,12 @@ import (
 	"fmt"
 	"time"
 
-	"k8s.io/contrib/cluster-autoscaler/config"
+	"k8s.io/contrib/cluster-autoscaler/cloudprovider"
 	"k8s.io/contrib/cluster-autoscaler/simulator"
-	"k8s.io/contrib/cluster-autoscaler/utils/gce"
 
 	kube_api "k8s.io/kubernetes/pkg/api"
 	kube_api_unversioned "k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/apis/policy"
 	"k8s.io/kubernetes/pkg/client/cache"
 	kube_client "k8s.io/kubernetes/pkg/client/unversioned"
 	"k8s.io/kubernetes/pkg/fields"
@@ -114,16 +114,35 @@ func (readyNodeLister *ReadyNodeLister) List() ([]*kube_api.Node, error) {
 		if node.Spec.Unschedulable {
 			continue
 		}
-		for _, condition := range node.Status.Conditions {
-			if condition.Type == kube_api.NodeReady && condition.Status == kube_api.ConditionTrue {
-				readyNodes = append(readyNodes, &nodes.Items[i])
-				break
-			}
+		if isNodeReady(&node) {
+			readyNodes = append(readyNodes, &nodes.Items[i])
 		}
 	}
 	return readyNodes, nil
 }
 
+// ListAll returns all nodes registered in the cluster, including not ready and unschedulable ones.
+func (readyNodeLister *ReadyNodeLister) ListAll() ([]*kube_api.Node, error) {
+	nodes, err := readyNodeLister.nodeLister.List()
+	if err != nil {
+		return []*kube_api.Node{}, err
+	}
+	allNodes := make([]*kube_api.Node, 0, len(nodes.Items))
+	for i := range nodes.Items {
+		allNodes = append(allNodes, &nodes.Items[i])
+	}
+	return allNodes, nil
+}
+
+func isNodeReady(node *kube_api.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == kube_api.NodeReady {
+			return condition.Status == kube_api.ConditionTrue
+		}
+	}
+	return false
+}
+
 // NewNodeLister builds a node lister.
 func NewNodeLister(kubeClient *kube_client.Client) *ReadyNodeLister {
 	listWatcher := cache.NewListWatchFromClient(kubeClient, "nodes", kube_api.NamespaceAll, fields.Everything())
@@ -135,6 +154,32 @@ func NewNodeLister(kubeClient *kube_client.Client) *ReadyNodeLister {
 	}
 }
 
+// PodDisruptionBudgetLister lists pod disruption budgets.
+type PodDisruptionBudgetLister struct {
+	store cache.Store
+}
+
+// List returns all pod disruption budgets.
+func (lister *PodDisruptionBudgetLister) List() ([]*policy.PodDisruptionBudget, error) {
+	items := lister.store.List()
+	pdbs := make([]*policy.PodDisruptionBudget, 0, len(items))
+	for _, item := range items {
+		pdbs = append(pdbs, item.(*policy.PodDisruptionBudget))
+	}
+	return pdbs, nil
+}
+
+// NewPodDisruptionBudgetLister builds a pod disruption budget lister.
+func NewPodDisruptionBudgetLister(kubeClient *kube_client.Client) *PodDisruptionBudgetLister {
+	listWatcher := cache.NewListWatchFromClient(kubeClient.PolicyClient, "poddisruptionbudgets", kube_api.NamespaceAll, fields.Everything())
+	store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	reflector := cache.NewReflector(listWatcher, &policy.PodDisruptionBudget{}, store, time.Hour)
+	reflector.Run()
+	return &PodDisruptionBudgetLister{
+		store: store,
+	}
+}
+
 // GetAllNodesAvailableTime returns time when the newest node became available for scheduler.
 // TODO: This function should use LastTransitionTime from NodeReady condition.
 func GetAllNodesAvailableTime(nodes []*kube_api.Node) time.Time {
@@ -227,68 +272,72 @@ func createNodeNameToInfoMap(pods []*kube_api.Pod, nodes []*kube_api.Node) map[s
 	return nodeNameToNodeInfo
 }
 
-// CheckMigsAndNodes checks if all migs have all required nodes.
-func CheckMigsAndNodes(nodes []*kube_api.Node, gceManager *gce.GceManager) error {
-	migCount := make(map[string]int)
-	migs := make(map[string]*config.MigConfig)
+// CheckGroupsAndNodes checks if all node groups have all required nodes. It returns the node groups
+// whose size doesn't match the number of ready nodes, together with the reason. Nodes that don't
+// belong to any node group, like the master, are skipped.
+func CheckGroupsAndNodes(nodes []*kube_api.Node, cloudProvider cloudprovider.CloudProvider) map[string]error {
+	groupCount := make(map[string]int)
 	for _, node := range nodes {
-		instanceConfig, err := config.InstanceConfigFromProviderId(node.Spec.ProviderID)
+		group, err := cloudProvider.NodeGroupForNode(node)
 		if err != nil {
-			return err
-		}
-
-		migConfig, err := gceManager.GetMigForInstance(instanceConfig)
-		if err != nil {
-			return err
+			glog.V(4).Infof("Skipping node %s without node group: %v", node.Name, err)
+			continue
 		}
-		url := migConfig.Url()
-		count, _ := migCount[url]
-		migCount[url] = count + 1
-		migs[url] = migConfig
+		groupCount[group.Id()]++
 	}
-	for url, mig := range migs {
-		size, err := gceManager.GetMigSize(mig)
+	unhealthyGroups := make(map[string]error)
+	for _, nodeGroup := range cloudProvider.NodeGroups() {
+		// Node groups without any ready node are checked too, their nodes may have failed to register.
+		count := groupCount[nodeGroup.Id()]
+		size, err := nodeGroup.TargetSize()
 		if err != nil {
-			return err
+			unhealthyGroups[nodeGroup.Id()] = err
+			continue
 		}
-		count := migCount[url]
-		if size != int64(count) {
-			return fmt.Errorf("wrong number of nodes for mig: %s expected: %d actual: %d", url, size, count)
+		if size != count {
+			unhealthyGroups[nodeGroup.Id()] = fmt.Errorf("wrong number of nodes for node group: %s expected: %d actual: %d",
+				nodeGroup.Id(), size, count)
 		}
 	}
-	return nil
+	return unhealthyGroups
 }
 
-// GetNodeInfosForMigs finds NodeInfos for all migs used to manage the given nodes. It also returns a mig to sample node mapping.
-// TODO(mwielgus): This returns map keyed by url, while most code (including scheduler) uses node.Name for a key.
-func GetNodeInfosForMigs(nodes []*kube_api.Node, gceManager *gce.GceManager, kubeClient *kube_client.Client) (map[string]*schedulercache.NodeInfo, error) {
+// GetNodeInfosForGroups finds NodeInfos for all node groups used to manage the given nodes. It also returns a node group to sample node mapping.
+// Node groups without any live node are represented by their template node, if the cloud provider can build one.
+// TODO(mwielgus): This returns map keyed by node group id, while most code (including scheduler) uses node.Name for a key.
+func GetNodeInfosForGroups(nodes []*kube_api.Node, cloudProvider cloudprovider.CloudProvider, requiredPodsLister simulator.RequiredPodsLister) (map[string]*schedulercache.NodeInfo, error) {
 	result := make(map[string]*schedulercache.NodeInfo)
 	for _, node := range nodes {
-		instanceConfig, err := config.InstanceConfigFromProviderId(node.Spec.ProviderID)
+		nodeGroup, err := cloudProvider.NodeGroupForNode(node)
 		if err != nil {
-			return map[string]*schedulercache.NodeInfo{}, err
+			glog.V(4).Infof("Skipping node %s without node group: %v", node.Name, err)
+			continue
+		}
+		id := nodeGroup.Id()
+		if _, found := result[id]; found {
+			continue
 		}
 
-		migConfig, err := gceManager.GetMigForInstance(instanceConfig)
+		nodeInfo, err := simulator.BuildNodeInfoForNode(node, requiredPodsLister)
 		if err != nil {
 			return map[string]*schedulercache.NodeInfo{}, err
 		}
-		url := migConfig.Url()
 
-		nodeInfo, err := simulator.BuildNodeInfoForNode(node, kubeClient)
+		result[id] = nodeInfo
+	}
+	for _, nodeGroup := range cloudProvider.NodeGroups() {
+		id := nodeGroup.Id()
+		if _, found := result[id]; found {
+			continue
+		}
+		nodeInfo, err := nodeGroup.TemplateNodeInfo()
 		if err != nil {
-			return map[string]*schedulercache.NodeInfo{}, err
+			if err != cloudprovider.ErrNotImplemented {
+				glog.Warningf("Failed to build template node for %s: %v", id, err)
+			}
+			continue
 		}
-
-		result[url] = nodeInfo
+		result[id] = nodeInfo
 	}
 	return result, nil
 }
-
-// BestExpansionOption picks the best cluster expansion option.
-func BestExpansionOption(expansionOptions []ExpansionOption) *ExpansionOption {
-	if len(expansionOptions) > 0 {
-		return &expansionOptions[0]
-	}
-	return nil
-}
@@ -0,0 +1,87 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	kube_api_v1 "k8s.io/kubernetes/pkg/api/v1"
+	apps_v1alpha1 "k8s.io/kubernetes/pkg/apis/apps/v1alpha1"
+	extensions_v1beta1 "k8s.io/kubernetes/pkg/apis/extensions/v1beta1"
+	kube_rest "k8s.io/kubernetes/pkg/client/restclient"
+	kube_client "k8s.io/kubernetes/pkg/client/unversioned"
+	"k8s.io/kubernetes/pkg/runtime"
+)
+
+// TestApiHandler returns the object that should be served for the given request.
+// Returning nil makes the server respond with 404. A returned Status with a code is served with
+// that http status code.
+type TestApiHandler func(req *http.Request) runtime.Object
+
+// NewTestKubeClient starts an in-process api server backed by the given handler
+// and returns a client talking to it. The caller is responsible for closing the server.
+func NewTestKubeClient(handler TestApiHandler) (*kube_client.Client, *httptest.Server) {
+	codec := kube_api.Codecs.LegacyCodec(kube_api_v1.SchemeGroupVersion, extensions_v1beta1.SchemeGroupVersion,
+		apps_v1alpha1.SchemeGroupVersion)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		obj := handler(req)
+		if obj == nil {
+			http.NotFound(w, req)
+			return
+		}
+		body, err := runtime.Encode(codec, obj)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", runtime.ContentTypeJSON)
+		if status, ok := obj.(*unversioned.Status); ok && status.Code != 0 {
+			w.WriteHeader(int(status.Code))
+		}
+		w.Write(body)
+	}))
+	client := kube_client.NewOrDie(&kube_rest.Config{
+		Host:          server.URL,
+		ContentConfig: kube_rest.ContentConfig{GroupVersion: &kube_api_v1.SchemeGroupVersion},
+	})
+	return client, server
+}
+
+// BuildTestPodList returns a PodList containing copies of the given pods.
+func BuildTestPodList(pods ...*kube_api.Pod) *kube_api.PodList {
+	list := &kube_api.PodList{}
+	for _, pod := range pods {
+		list.Items = append(list.Items, *pod)
+	}
+	return list
+}
+
+// DecodeTestObject decodes the body of the request sent to the test api server into obj.
+func DecodeTestObject(req *http.Request, obj runtime.Object) runtime.Object {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		panic(err)
+	}
+	if err := runtime.DecodeInto(kube_api.Codecs.UniversalDecoder(), body, obj); err != nil {
+		panic(err)
+	}
+	return obj
+}
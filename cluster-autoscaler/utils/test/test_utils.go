@@ -55,6 +55,9 @@ func BuildTestNode(name string, cpu int64, mem int64) *kube_api.Node {
 		ObjectMeta: kube_api.ObjectMeta{
 			Name: name,
 		},
+		Spec: kube_api.NodeSpec{
+			ProviderID: name,
+		},
 		Status: kube_api.NodeStatus{
 			Capacity: kube_api.ResourceList{
 				kube_api.ResourcePods: *resource.NewQuantity(100, resource.DecimalSI),
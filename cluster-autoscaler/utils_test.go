@@ -19,6 +19,7 @@ package main
 import (
 	"testing"
 
+	testprovider "k8s.io/contrib/cluster-autoscaler/cloudprovider/test"
 	"k8s.io/contrib/cluster-autoscaler/simulator"
 	. "k8s.io/contrib/cluster-autoscaler/utils/test"
 
@@ -51,3 +52,54 @@ func TestFilterOutSchedulable(t *testing.T) {
 	assert.Equal(t, p1, res2[0])
 	assert.Equal(t, p2, res2[1])
 }
+
+func TestCheckGroupsAndNodes(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng2", n2)
+
+	unhealthy := CheckGroupsAndNodes([]*kube_api.Node{n1, n2}, provider)
+	assert.Empty(t, unhealthy)
+
+	provider.AddNodeGroup("ng1", 1, 10, 2)
+	unhealthy = CheckGroupsAndNodes([]*kube_api.Node{n1, n2}, provider)
+	assert.Equal(t, 1, len(unhealthy))
+	assert.Error(t, unhealthy["ng1"])
+
+	// A node without node group, like the master, is skipped.
+	master := BuildTestNode("master", 1000, 1000)
+	unhealthy = CheckGroupsAndNodes([]*kube_api.Node{n1, n2, master}, provider)
+	assert.Equal(t, 1, len(unhealthy))
+	assert.Error(t, unhealthy["ng1"])
+
+	// A node group whose nodes all failed to register is unhealthy.
+	provider.AddNodeGroup("ng3", 0, 10, 2)
+	unhealthy = CheckGroupsAndNodes([]*kube_api.Node{n1, n2}, provider)
+	assert.Equal(t, 2, len(unhealthy))
+	assert.Error(t, unhealthy["ng3"])
+
+	// An empty node group is healthy.
+	provider.AddNodeGroup("ng3", 0, 10, 0)
+	unhealthy = CheckGroupsAndNodes([]*kube_api.Node{n1, n2}, provider)
+	assert.Equal(t, 1, len(unhealthy))
+}
+
+func TestGetNodeInfosForGroups(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	master := BuildTestNode("master", 1000, 1000)
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNode("ng1", n1)
+
+	// The master doesn't belong to any node group and is skipped.
+	nodeInfos, err := GetNodeInfosForGroups([]*kube_api.Node{n1, master}, provider, simulator.NewStaticRequiredPodsLister(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(nodeInfos))
+	assert.Equal(t, "n1", nodeInfos["ng1"].Node().Name)
+}
@@ -0,0 +1,294 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	testprovider "k8s.io/contrib/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/contrib/cluster-autoscaler/simulator"
+
+	kube_api "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/meta"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/apis/policy"
+	"k8s.io/kubernetes/pkg/runtime"
+	"k8s.io/kubernetes/pkg/util/yaml"
+	"k8s.io/kubernetes/plugin/pkg/scheduler/schedulercache"
+)
+
+// WhatIfSnapshot is a cluster state on which the autoscaler decisions are simulated offline.
+// Nodes, pods and templates are in the v1 api format, as printed by kubectl get -o yaml/json.
+type WhatIfSnapshot struct {
+	// Nodes registered in the cluster.
+	Nodes []json.RawMessage `json:"nodes"`
+	// Pods, both scheduled and pending. Pending pods are considered unschedulable.
+	Pods []json.RawMessage `json:"pods"`
+	// PodDisruptionBudgets in the policy/v1alpha1 api format.
+	PodDisruptionBudgets []json.RawMessage `json:"podDisruptionBudgets,omitempty"`
+	// Migs managed by cluster autoscaler.
+	Migs []WhatIfMig `json:"migs"`
+}
+
+// WhatIfMig describes a mig in WhatIfSnapshot.
+type WhatIfMig struct {
+	// Url or any other unique name of the mig.
+	Url string `json:"url"`
+	// MinSize of the mig.
+	MinSize int `json:"minSize"`
+	// MaxSize of the mig.
+	MaxSize int `json:"maxSize"`
+	// TargetSize of the mig. Defaults to the number of its nodes.
+	TargetSize *int `json:"targetSize,omitempty"`
+	// Nodes are names of the nodes belonging to the mig.
+	Nodes []string `json:"nodes"`
+	// Template is an optional node used as the template for migs without nodes.
+	Template json.RawMessage `json:"template,omitempty"`
+}
+
+// LoadWhatIfSnapshot reads a YAML or JSON snapshot from the given file.
+func LoadWhatIfSnapshot(path string) (*WhatIfSnapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	snapshot := &WhatIfSnapshot{}
+	if err := yaml.NewYAMLOrJSONDecoder(file, 4096).Decode(snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return snapshot, nil
+}
+
+// RunWhatIf simulates a single cluster autoscaler loop on the snapshot and prints the decisions and
+// their reasons to out. Only the cluster-independent parts of the context (expander, estimator and
+// scale down settings) are used, the rest is replaced by in-memory fakes backed by the snapshot.
+// Nodes are assumed to have been unneeded for long enough to be removed. Scale down uses the
+// fast removal check, which assumes that all controllers referenced by the pods exist.
+func RunWhatIf(snapshot *WhatIfSnapshot, context AutoscalingContext, out io.Writer) error {
+	allNodes := make([]*kube_api.Node, 0, len(snapshot.Nodes))
+	for _, raw := range snapshot.Nodes {
+		node := &kube_api.Node{}
+		if err := decodeWhatIfObject(raw, whatIfNodeKind, node); err != nil {
+			return fmt.Errorf("failed to decode node: %v", err)
+		}
+		allNodes = append(allNodes, node)
+	}
+	allPods := make([]*kube_api.Pod, 0, len(snapshot.Pods))
+	for _, raw := range snapshot.Pods {
+		pod := &kube_api.Pod{}
+		if err := decodeWhatIfObject(raw, whatIfPodKind, pod); err != nil {
+			return fmt.Errorf("failed to decode pod: %v", err)
+		}
+		allPods = append(allPods, pod)
+	}
+	pdbs := make([]*policy.PodDisruptionBudget, 0, len(snapshot.PodDisruptionBudgets))
+	for _, raw := range snapshot.PodDisruptionBudgets {
+		pdb := &policy.PodDisruptionBudget{}
+		if err := decodeWhatIfObject(raw, whatIfPodDisruptionBudgetKind, pdb); err != nil {
+			return fmt.Errorf("failed to decode pod disruption budget: %v", err)
+		}
+		pdbs = append(pdbs, pdb)
+	}
+
+	provider := testprovider.NewTestCloudProvider(func(id string, size int) error {
+		fmt.Fprintf(out, "Scale up: %s to %d nodes\n", id, size)
+		return nil
+	}, nil)
+	for _, mig := range snapshot.Migs {
+		size := len(mig.Nodes)
+		if mig.TargetSize != nil {
+			size = *mig.TargetSize
+		}
+		nodeGroup := provider.AddNodeGroup(mig.Url, mig.MinSize, mig.MaxSize, size)
+		for _, name := range mig.Nodes {
+			provider.AddNode(mig.Url, &kube_api.Node{ObjectMeta: kube_api.ObjectMeta{Name: name}})
+		}
+		if len(mig.Template) > 0 {
+			template := &kube_api.Node{}
+			if err := decodeWhatIfObject(mig.Template, whatIfNodeKind, template); err != nil {
+				return fmt.Errorf("failed to decode template of %s: %v", mig.Url, err)
+			}
+			nodeInfo := schedulercache.NewNodeInfo()
+			if err := nodeInfo.SetNode(template); err != nil {
+				return err
+			}
+			nodeGroup.SetTemplateNodeInfo(nodeInfo)
+		}
+	}
+
+	context.CloudProvider = provider
+	context.KubeClient = nil
+	context.RequiredPodsLister = simulator.NewStaticRequiredPodsLister(allPods)
+	context.Recorder = &whatIfRecorder{out: out}
+	// Only the general predicates are checked, as the other default predicates need
+	// informers backed by a real api server.
+	context.PredicateChecker = simulator.NewTestPredicateChecker()
+	context.StatusTracker = NewStatusTracker()
+	context.ScaleUpTracker = NewScaleUpTracker(0)
+
+	nodes := make([]*kube_api.Node, 0, len(allNodes))
+	for _, node := range allNodes {
+		if isNodeReady(node) && !node.Spec.Unschedulable {
+			nodes = append(nodes, node)
+		}
+	}
+	scheduledPods := make([]*kube_api.Pod, 0)
+	pendingPods := make([]*kube_api.Pod, 0)
+	for _, pod := range allPods {
+		if pod.Status.Phase == kube_api.PodSucceeded || pod.Status.Phase == kube_api.PodFailed {
+			continue
+		}
+		if pod.Spec.NodeName == "" {
+			pendingPods = append(pendingPods, pod)
+		} else {
+			scheduledPods = append(scheduledPods, pod)
+		}
+	}
+	fmt.Fprintf(out, "Snapshot: %d nodes (%d ready), %d scheduled pods, %d pending pods, %d migs\n",
+		len(allNodes), len(nodes), len(scheduledPods), len(pendingPods), len(snapshot.Migs))
+
+	context.UnhealthyNodeGroups = make(map[string]bool)
+	for id, err := range CheckGroupsAndNodes(nodes, provider) {
+		fmt.Fprintf(out, "Node group %s is not autoscaled: %v\n", id, err)
+		context.UnhealthyNodeGroups[id] = true
+	}
+
+	fmt.Fprintf(out, "\n== Scale up\n")
+	unschedulablePods := FilterOutSchedulable(pendingPods, nodes, scheduledPods, context.PredicateChecker)
+	schedulable := make(map[*kube_api.Pod]bool)
+	for _, pod := range pendingPods {
+		schedulable[pod] = true
+	}
+	for _, pod := range unschedulablePods {
+		schedulable[pod] = false
+	}
+	for _, pod := range pendingPods {
+		if schedulable[pod] {
+			fmt.Fprintf(out, "Pod %s/%s fits on an existing node, ignored in scale up\n", pod.Namespace, pod.Name)
+		}
+	}
+	scaledUp := false
+	if len(unschedulablePods) == 0 {
+		fmt.Fprintf(out, "No unschedulable pods\n")
+	} else {
+		var err error
+		scaledUp, err = ScaleUp(&context, unschedulablePods, nodes)
+		if err != nil {
+			fmt.Fprintf(out, "Scale up failed: %v\n", err)
+		} else if !scaledUp {
+			fmt.Fprintf(out, "No scale up\n")
+		}
+	}
+
+	fmt.Fprintf(out, "\n== Scale down\n")
+	if scaledUp {
+		fmt.Fprintf(out, "Skipped, the cluster was scaled up\n")
+		return nil
+	}
+	unneededNodes := FindUnneededNodes(&context, nodes, map[string]time.Time{}, scheduledPods, pdbs)
+	if len(unneededNodes) == 0 {
+		fmt.Fprintf(out, "No unneeded nodes\n")
+		return nil
+	}
+	for _, node := range nodes {
+		if _, found := unneededNodes[node.Name]; found {
+			fmt.Fprintf(out, "Node %s is unneeded\n", node.Name)
+			// The snapshot has no history, treat the node as unneeded since ever.
+			unneededNodes[node.Name] = time.Time{}
+		}
+	}
+	candidates, removalBudget, skipped := getScaleDownCandidates(&context, nodes, unneededNodes, time.Now())
+	for _, node := range nodes {
+		if reason, found := skipped[node.Name]; found {
+			fmt.Fprintf(out, "Node %s can't be removed: %s\n", node.Name, reason)
+		}
+	}
+	if len(candidates) == 0 {
+		fmt.Fprintf(out, "No candidates for scale down\n")
+		return nil
+	}
+
+	emptyNodes := simulator.FindEmptyNodesToRemove(candidates, scheduledPods)
+	emptyNodes = limitToRemovalBudget(&context, emptyNodes, removalBudget, context.MaxEmptyBulkDelete)
+	if len(emptyNodes) > 0 {
+		for _, node := range emptyNodes {
+			fmt.Fprintf(out, "Scale down: empty node %s would be removed\n", node.Name)
+		}
+		return nil
+	}
+	nodesToRemove, err := simulator.FindNodesToRemove(candidates, nodes, scheduledPods, pdbs,
+		nil, context.PredicateChecker, context.MaxNonEmptyBulkDelete, true)
+	if err != nil {
+		return err
+	}
+	nodesToRemove = limitNodesToRemoveToRemovalBudget(&context, nodesToRemove, removalBudget)
+	if len(nodesToRemove) == 0 {
+		fmt.Fprintf(out, "No node can be removed\n")
+	}
+	for _, toRemove := range nodesToRemove {
+		podNames := make([]string, 0, len(toRemove.PodsToReschedule))
+		for _, pod := range toRemove.PodsToReschedule {
+			podNames = append(podNames, pod.Namespace+"/"+pod.Name)
+		}
+		fmt.Fprintf(out, "Scale down: node %s would be removed, rescheduling pods: %s\n", toRemove.Node.Name,
+			strings.Join(podNames, ", "))
+	}
+	return nil
+}
+
+var (
+	whatIfNodeKind                = unversioned.GroupVersionKind{Version: "v1", Kind: "Node"}
+	whatIfPodKind                 = unversioned.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	whatIfPodDisruptionBudgetKind = unversioned.GroupVersionKind{Group: policy.GroupName, Version: "v1alpha1",
+		Kind: "PodDisruptionBudget"}
+)
+
+// decodeWhatIfObject decodes the object, using defaultGVK if the object doesn't specify its kind.
+func decodeWhatIfObject(raw json.RawMessage, defaultGVK unversioned.GroupVersionKind, into runtime.Object) error {
+	_, _, err := kube_api.Codecs.UniversalDecoder().Decode(raw, &defaultGVK, into)
+	return err
+}
+
+// whatIfRecorder prints events instead of sending them to the api server.
+type whatIfRecorder struct {
+	out io.Writer
+}
+
+func (recorder *whatIfRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	name := "unknown"
+	if accessor, err := meta.Accessor(object); err == nil {
+		name = accessor.GetName()
+		if accessor.GetNamespace() != "" {
+			name = accessor.GetNamespace() + "/" + name
+		}
+	}
+	fmt.Fprintf(recorder.out, "Event %s %s: %s\n", reason, name, message)
+}
+
+func (recorder *whatIfRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	recorder.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (recorder *whatIfRecorder) PastEventf(object runtime.Object, timestamp unversioned.Time, eventtype, reason, messageFmt string, args ...interface{}) {
+	recorder.Eventf(object, eventtype, reason, messageFmt, args...)
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/contrib/cluster-autoscaler/estimator"
+	"k8s.io/contrib/cluster-autoscaler/expander"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const whatIfSnapshotYaml = `
+nodes:
+  - metadata:
+      name: n1
+    status:
+      capacity: {cpu: "1", memory: 1Gi, pods: "110"}
+      conditions:
+      - {type: Ready, status: "True"}
+  - metadata:
+      name: n2
+    status:
+      capacity: {cpu: "1", memory: 1Gi, pods: "110"}
+      conditions:
+      - {type: Ready, status: "True"}
+pods:
+  - metadata:
+      name: p1
+      namespace: default
+      annotations:
+        kubernetes.io/created-by: '{"kind":"SerializedReference","apiVersion":"v1","reference":{"kind":"ReplicaSet","namespace":"default","name":"rs"}}'
+    spec:
+      nodeName: n1
+      containers:
+      - name: c
+        resources:
+          requests: {cpu: 600m}
+  - metadata:
+      name: static
+      namespace: kube-system
+      annotations:
+        kubernetes.io/config.mirror: mirror
+    spec:
+      nodeName: n2
+      containers:
+      - name: c
+  - metadata:
+      name: p2
+      namespace: default
+    spec:
+      containers:
+      - name: c
+        resources:
+          requests: {cpu: "3"}
+migs:
+  - url: small
+    minSize: 1
+    maxSize: 5
+    nodes: [n1, n2]
+  - url: big
+    minSize: 0
+    maxSize: 5
+    template:
+      metadata:
+        name: big-template
+      status:
+        capacity: {cpu: "4", memory: 4Gi, pods: "110"}
+        allocatable: {cpu: "4", memory: 4Gi, pods: "110"}
+`
+
+func TestRunWhatIf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "whatif")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "snapshot.yaml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(whatIfSnapshotYaml), 0644))
+
+	snapshot, err := LoadWhatIfSnapshot(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(snapshot.Nodes))
+
+	context := AutoscalingContext{
+		ExpanderStrategy:              expander.NewRandomStrategy(),
+		EstimatorName:                 estimator.BinpackingEstimatorName,
+		ScaleDownUtilizationThreshold: 0.5,
+		MaxEmptyBulkDelete:            10,
+		MaxNonEmptyBulkDelete:         1,
+	}
+	out := &bytes.Buffer{}
+	assert.NoError(t, RunWhatIf(snapshot, context, out))
+	assert.Contains(t, out.String(), "Scale up: big to 1 nodes")
+	assert.Contains(t, out.String(), "Event TriggeredScaleUp default/p2")
+	assert.Contains(t, out.String(), "Skipped, the cluster was scaled up")
+
+	// Without the pending pod the empty node can go.
+	snapshot.Pods = snapshot.Pods[:2]
+	out = &bytes.Buffer{}
+	assert.NoError(t, RunWhatIf(snapshot, context, out))
+	assert.Contains(t, out.String(), "No unschedulable pods")
+	assert.Contains(t, out.String(), "Node n2 is unneeded")
+	assert.Contains(t, out.String(), "Scale down: empty node n2 would be removed")
+
+	// A replicated pod on n2 can be moved to n1, unless a budget protects it.
+	snapshot.Pods = append(snapshot.Pods, json.RawMessage(`{"metadata": {"name": "p3", "namespace": "default",
+		"labels": {"app": "p3"}, "annotations": {"kubernetes.io/created-by":
+		"{\"kind\":\"SerializedReference\",\"apiVersion\":\"v1\",\"reference\":{\"kind\":\"ReplicaSet\",\"namespace\":\"default\",\"name\":\"rs3\"}}"}},
+		"spec": {"nodeName": "n2", "containers": [{"name": "c", "resources": {"requests": {"cpu": "100m"}}}]}}`))
+	out = &bytes.Buffer{}
+	assert.NoError(t, RunWhatIf(snapshot, context, out))
+	assert.Contains(t, out.String(), "Scale down: node n2 would be removed, rescheduling pods: default/p3")
+
+	snapshot.PodDisruptionBudgets = []json.RawMessage{json.RawMessage(`{"metadata": {"name": "p3", "namespace": "default"},
+		"spec": {"selector": {"matchLabels": {"app": "p3"}}}, "status": {"disruptionAllowed": false}}`)}
+	out = &bytes.Buffer{}
+	assert.NoError(t, RunWhatIf(snapshot, context, out))
+	assert.NotContains(t, out.String(), "would be removed")
+}